@@ -13,14 +13,13 @@ context('PromLens with no shared page', () => {
               id: 1,
               orgID: 1,
               name: 'PromLabs Demo',
-              type: 'prometheus',
               access: 'proxy',
               url: 'https://demo.promlabs.com',
-              password: '',
-              user: '',
-              basicAuth: false,
               isDefault: true,
-              jsonData: {},
+              withCredentials: false,
+              basicAuth: false,
+              customHeaders: false,
+              health: null,
             },
           ],
           pageState: null,
@@ -69,14 +68,13 @@ context('PromLens with a shared page', () => {
               id: 1,
               orgID: 1,
               name: 'PromLabs Demo',
-              type: 'prometheus',
               access: 'proxy',
               url: 'https://demo.promlabs.com',
-              password: '',
-              user: '',
-              basicAuth: false,
               isDefault: true,
-              jsonData: {},
+              withCredentials: false,
+              basicAuth: false,
+              customHeaders: false,
+              health: null,
             },
           ],
           pageState: {
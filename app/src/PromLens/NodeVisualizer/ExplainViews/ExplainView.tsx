@@ -4,7 +4,7 @@ import AggregationExplainView from './Aggregation';
 import BinaryExprExplainView from './BinaryExpr/BinaryExpr';
 import SelectorExplainView from './Selector';
 import { formatDuration } from '../../../utils/utils';
-import funcDocs from '../../../promql/functionDocs';
+import { functionDocs } from '../../../promql/functionDocs';
 import { Alert } from 'react-bootstrap';
 import { escapeString, containsPlaceholders } from '../../../promql/utils';
 import { PromAPI } from '../../../promAPI/promAPI';
@@ -39,7 +39,9 @@ const ExplainView: FC<ExplainViewProps> = ({ node, promAPI }) => {
             function{node.args.length > 0 ? ' on the provided inputs' : ''}.
           </p>
           <hr />
-          {funcDocs[node.func.name]}
+          {functionDocs[node.func.name] !== undefined && (
+            <div dangerouslySetInnerHTML={{ __html: functionDocs[node.func.name] }} />
+          )}
         </Alert>
       );
     case nodeType.matrixSelector:
@@ -9,7 +9,7 @@ import { AppState, ServerSettings } from '../state/state';
 import ServerOptionsEditor from './ServerOptionsEditor';
 import { Row, Col, Alert, Button, Toast } from 'react-bootstrap';
 import LinkSharer from './LinkSharer/LinkSharer';
-import { GrafanaDataSourceSettings, PathPrefixProps } from '../types/types';
+import { GrafanaDataSourceSettings, GrafanaOrg, PathPrefixProps, PrometheusServer } from '../types/types';
 import { PromAPI } from '../promAPI/promAPI';
 import { FaCog } from 'react-icons/fa';
 import SettingsEditor, { SettingsContext, Settings } from './SettingsEditor';
@@ -23,6 +23,8 @@ interface PromLensUIStateProps {
 
 interface PromLensUIOwnProps {
   datasources: GrafanaDataSourceSettings[];
+  grafanaOrgs: GrafanaOrg[];
+  prometheusServers: PrometheusServer[];
   initialTrigger: boolean;
 }
 
@@ -30,6 +32,8 @@ const PromLens: FC<PromLensUIStateProps & PromLensUIOwnProps & PathPrefixProps>
   pathPrefix,
   serverSettings,
   datasources,
+  grafanaOrgs,
+  prometheusServers,
   selectedNode,
   initialTrigger,
   retriggerIndex,
@@ -57,7 +61,12 @@ const PromLens: FC<PromLensUIStateProps & PromLensUIOwnProps & PathPrefixProps>
       <Row className="server-settings" noGutters>
         <Col>
           <div>
-            <ServerOptionsEditor datasources={datasources} pathPrefix={pathPrefix} />
+            <ServerOptionsEditor
+              datasources={datasources}
+              grafanaOrgs={grafanaOrgs}
+              prometheusServers={prometheusServers}
+              pathPrefix={pathPrefix}
+            />
           </div>
         </Col>
         <Col xs="auto">
@@ -1,10 +1,13 @@
 import ReactDOM from 'react-dom';
 import getNodeAnnotations from './NodeAnnotations';
 import ASTNode, { nodeType, aggregationType } from '../../../promql/ast';
-import { functionSignatures } from '../../../promql/functionSignatures';
+import { functionSignatures, setFunctionSignatures } from '../../../promql/functions';
+import { testFunctionSignatures } from '../../../promql/testFunctionSignatures';
 import serializeNode from '../../../promql/serialize';
 import { NodeQueryResult } from '../../../state/state';
 
+setFunctionSignatures(testFunctionSignatures);
+
 describe('nodeAnnotations', () => {
   describe('should suggest and apply the right actions', () => {
     const defaultQueryResult: NodeQueryResult = {
@@ -2,7 +2,7 @@ import React from 'react';
 import ASTNode, { nodeType, MatrixSelector, Call, VectorSelector, aggregationType } from '../../../promql/ast';
 import { NodeQueryResult } from '../../../state/state';
 import { hasCounterSuffix } from '../../../utils/utils';
-import { functionSignatures } from '../../../promql/functionSignatures';
+import { functionSignatures } from '../../../promql/functions';
 
 interface NodeAction {
   title: React.ReactElement;
@@ -40,7 +40,7 @@ import { getAllDescendantIDs } from '../../../state/reducers';
 import { Button, OverlayTrigger, Tooltip } from 'react-bootstrap';
 import NodeEditor from './NodeEditor/NodeEditor';
 import ReactResizeDetector from 'react-resize-detector';
-import { functionSignatures } from '../../../promql/functionSignatures';
+import { functionSignatures } from '../../../promql/functions';
 import { PromAPI } from '../../../promAPI/promAPI';
 import { PathPrefixProps } from '../../../types/types';
 import { Settings, SettingsContext } from '../../../PromLens/SettingsEditor';
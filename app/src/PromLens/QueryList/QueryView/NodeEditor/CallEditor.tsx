@@ -1,6 +1,6 @@
 import React, { FC, useMemo } from 'react';
 import ASTNode, { Call, Func, nodeType } from '../../../../promql/ast';
-import { functionSignatures } from '../../../../promql/functionSignatures';
+import { functionSignatures } from '../../../../promql/functions';
 import { nodeValueType } from '../../../../promql/utils';
 import { NodeConstraints } from '../types';
 import { Form } from 'react-bootstrap';
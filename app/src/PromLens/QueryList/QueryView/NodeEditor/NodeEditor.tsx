@@ -21,7 +21,7 @@ import { denormalizeAST } from '../../../../state/normalizeAST';
 import { FaCheck } from 'react-icons/fa';
 import { allowedChildValueTypes, getNodeChildren, nodeValueType, anyValueType } from '../../../../promql/utils';
 import { NodeConstraints } from '../types';
-import { functionSignatures } from '../../../../promql/functionSignatures';
+import { functionSignatures } from '../../../../promql/functions';
 import { Form, Button, Tabs, Tab } from 'react-bootstrap';
 import { PromAPI } from '../../../../promAPI/promAPI';
 
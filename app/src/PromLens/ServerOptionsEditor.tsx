@@ -5,7 +5,7 @@ import { connect } from 'react-redux';
 import * as actions from '../state/actions';
 import { AppState, ServerSettings } from '../state/state';
 import { Form, InputGroup, Alert, FormControl } from 'react-bootstrap';
-import { GrafanaDataSourceSettings, PathPrefixProps } from '../types/types';
+import { GrafanaDataSourceSettings, GrafanaOrg, PathPrefixProps, PrometheusServer } from '../types/types';
 import { PromAPI } from '../promAPI/promAPI';
 import { grafanaDatasourceToServerSettings } from '../state/utils';
 import { QueryResult } from './QueryList/QueryView/QueryResultTypes';
@@ -16,6 +16,8 @@ interface ServerOptionsEditorStateProps {
 
 interface ServerOptionsEditorOwnProps {
   datasources: GrafanaDataSourceSettings[];
+  grafanaOrgs: GrafanaOrg[];
+  prometheusServers: PrometheusServer[];
 }
 
 interface ServerOptionsEditorDispatchProps {
@@ -24,7 +26,7 @@ interface ServerOptionsEditorDispatchProps {
 
 const ServerOptionsEditor: FC<
   ServerOptionsEditorStateProps & ServerOptionsEditorOwnProps & ServerOptionsEditorDispatchProps & PathPrefixProps
-> = ({ serverSettings, datasources, setServerSettings, pathPrefix }) => {
+> = ({ serverSettings, datasources, grafanaOrgs, prometheusServers, setServerSettings, pathPrefix }) => {
   const [inputURL, setInputURL] = useState(serverSettings.url);
 
   // Server settings go from:
@@ -62,12 +64,23 @@ const ServerOptionsEditor: FC<
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [healthQuery.data, healthQuery.loading]);
 
-  const grafanaDatasourceSelector = (
+  const selectedServer =
+    tentativeServerSettings.datasourceID === null
+      ? prometheusServers.find((srv) => srv.url === tentativeServerSettings.url)
+      : undefined;
+
+  const serverSelector = (
     <Form.Control
       as="select"
       id="select-server"
       // TODO: If someone has a saved datasource ID, we need to check if it still exists.
-      value={tentativeServerSettings.datasourceID !== null ? tentativeServerSettings.datasourceID.toString() : 'manual'}
+      value={
+        tentativeServerSettings.datasourceID !== null
+          ? tentativeServerSettings.datasourceID.toString()
+          : selectedServer !== undefined
+          ? `server:${selectedServer.name}`
+          : 'manual'
+      }
       onChange={(e: React.ChangeEvent<HTMLInputElement>) => {
         if (e.currentTarget.value === 'manual') {
           setTentativeServerSettings({
@@ -75,6 +88,19 @@ const ServerOptionsEditor: FC<
             access: 'direct',
             datasourceID: null,
           });
+        } else if (e.currentTarget.value.startsWith('server:')) {
+          const name = e.currentTarget.value.slice('server:'.length);
+          const srv = prometheusServers.find((srv) => srv.name === name);
+          if (srv === undefined) {
+            throw new Error(`Could not look up Prometheus server with name ${name}`);
+          }
+
+          setTentativeServerSettings({
+            url: srv.url,
+            access: 'direct',
+            datasourceID: null,
+            withCredentials: false,
+          });
         } else {
           const ds = datasources.find((ds) => ds.id === parseInt(e.currentTarget.value));
           if (ds === undefined) {
@@ -89,12 +115,38 @@ const ServerOptionsEditor: FC<
       <option key="manual" value="manual">
         Manual server entry
       </option>
-      <option disabled>--- Datasources from Grafana ---</option>
-      {datasources.map(({ name, id }) => (
-        <option key={id} value={id}>
-          {name}
+      {prometheusServers.length > 0 && <option disabled>--- Prometheus servers ---</option>}
+      {prometheusServers.map(({ name, description }) => (
+        <option key={`server:${name}`} value={`server:${name}`} title={description}>
+          {description !== '' ? `${name} (${description})` : name}
         </option>
       ))}
+      {grafanaOrgs.length === 0 ? (
+        <>
+          {datasources.length > 0 && <option disabled>--- Datasources from Grafana ---</option>}
+          {datasources.map(({ name, id }) => (
+            <option key={id} value={id}>
+              {name}
+            </option>
+          ))}
+        </>
+      ) : (
+        grafanaOrgs.map((org) => {
+          const orgDatasources = datasources.filter((ds) => ds.orgID === org.id);
+          return (
+            orgDatasources.length > 0 && (
+              <React.Fragment key={`org:${org.id}`}>
+                <option disabled>--- Datasources from Grafana ({org.name}) ---</option>
+                {orgDatasources.map(({ name, id }) => (
+                  <option key={id} value={id}>
+                    {name}
+                  </option>
+                ))}
+              </React.Fragment>
+            )
+          );
+        })
+      )}
     </Form.Control>
   );
 
@@ -130,11 +182,11 @@ const ServerOptionsEditor: FC<
             style={{ borderColor: 'rgba(52, 79, 113, 0.2)', borderRadius: 0 }}
           />
         )}
-        {datasources.length > 0 &&
+        {(datasources.length > 0 || prometheusServers.length > 0) &&
           (tentativeServerSettings.datasourceID === null ? (
-            <InputGroup.Append>{grafanaDatasourceSelector}</InputGroup.Append>
+            <InputGroup.Append>{serverSelector}</InputGroup.Append>
           ) : (
-            <>{grafanaDatasourceSelector}</>
+            <>{serverSelector}</>
           ))}
       </InputGroup>
 
@@ -145,6 +197,20 @@ const ServerOptionsEditor: FC<
         </Alert>
       )}
 
+      {(() => {
+        const ds = datasources.find((ds) => ds.id === tentativeServerSettings.datasourceID);
+        if (ds === undefined || !ds.health || ds.health.up) {
+          return null;
+        }
+
+        return (
+          <Alert variant="warning" className="parse-error">
+            <strong>Warning:</strong> This Grafana datasource failed its last health check at{' '}
+            {new Date(ds.health.lastCheck).toLocaleString()}: {ds.health.error}
+          </Alert>
+        );
+      })()}
+
       {(() => {
         const ds = datasources.find((ds) => ds.id === tentativeServerSettings.datasourceID);
         if (ds === undefined || ds.access === 'proxy') {
@@ -161,7 +227,7 @@ const ServerOptionsEditor: FC<
               </Alert>
             )}
 
-            {ds.jsonData && ds.jsonData.hasOwnProperty('httpHeaderName1') && (
+            {ds.customHeaders && (
               <Alert variant="warning" className="parse-error">
                 <strong>Warning:</strong> This Grafana datasource uses custom headers in combination with direct
                 browser-based access. This is not yet supported in PromLens (Grafana does not supply the required header
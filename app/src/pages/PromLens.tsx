@@ -8,7 +8,8 @@ import PromLensUI from '../PromLens/PromLensUI';
 import { PageConfig, PathPrefixProps } from '../types/types';
 import { grafanaDatasourceToServerSettings } from '../state/utils';
 import { nodeType, valueType, matchType } from '../promql/ast';
-import { functionSignatures } from '../promql/functionSignatures';
+import { loadFunctionSignatures } from '../promql/functions';
+import { loadFunctionDocs } from '../promql/functionDocs';
 
 const store = createStore(appReducer);
 
@@ -118,7 +119,7 @@ const PromLens: FC<PathPrefixProps> = ({ pathPrefix }) => {
       .map(([a, b]) => [a, decodeURIComponent(b)])
   );
 
-  // Load the initial page configuration, datasources, and shared page state.
+  // Load the initial page configuration, datasources, shared page state, and function signatures and docs.
   useEffect(() => {
     let statusCode = 0;
     let statusText = '';
@@ -137,6 +138,12 @@ const PromLens: FC<PathPrefixProps> = ({ pathPrefix }) => {
         }
         return res;
       })
+      .then((pageConfig: PageConfig) => {
+        const enableExperimental = pageConfig.parserFeatures.includes('promql-experimental-functions');
+        return Promise.all([loadFunctionSignatures(pathPrefix, enableExperimental), loadFunctionDocs(pathPrefix)]).then(
+          () => pageConfig
+        );
+      })
       .then((pageConfig: PageConfig) => {
         if (queryParams.example !== undefined) {
           pageConfig.pageState = examplePageState;
@@ -221,6 +228,14 @@ const PromLens: FC<PathPrefixProps> = ({ pathPrefix }) => {
         </Alert>
       )}
 
+      {pageConfig?.grafanaDatasourceStatus?.error && (
+        <Alert variant="warning">
+          <strong>Refreshing Grafana datasources failed:</strong> {pageConfig.grafanaDatasourceStatus.error}
+          {pageConfig.grafanaDatasourceStatus.stale &&
+            ` (showing datasources from ${new Date(pageConfig.grafanaDatasourceStatus.lastUpdate).toLocaleString()})`}
+        </Alert>
+      )}
+
       {stateImportError && (
         <Alert variant="danger">
           <strong>Importing shared page state failed:</strong> {stateImportError}
@@ -229,7 +244,13 @@ const PromLens: FC<PathPrefixProps> = ({ pathPrefix }) => {
 
       {stateImportError === null && pageConfigError === null && pageConfig !== null && (
         <Provider store={store}>
-          <PromLensUI initialTrigger={!!queryParams.q} pathPrefix={pathPrefix} datasources={pageConfig.grafanaDatasources} />
+          <PromLensUI
+            initialTrigger={!!queryParams.q}
+            pathPrefix={pathPrefix}
+            datasources={pageConfig.grafanaDatasources}
+            grafanaOrgs={pageConfig.grafanaOrgs || []}
+            prometheusServers={pageConfig.prometheusServers}
+          />
         </Provider>
       )}
     </Container>
@@ -0,0 +1,17 @@
+// The PromQL function documentation of the Prometheus version whose parser is built into
+// the PromLens backend, rendered as HTML. It is loaded from the /api/function_docs endpoint
+// (see loadFunctionDocs()) before the UI is rendered.
+export const functionDocs: Record<string, string> = {};
+
+export const loadFunctionDocs = (pathPrefix: string): Promise<void> =>
+  fetch(`${pathPrefix}/api/function_docs`)
+    .then((res) => {
+      if (res.status !== 200) {
+        throw new Error(`Loading function docs failed: ${res.statusText}`);
+      }
+      return res.json();
+    })
+    .then((docs: Record<string, string>) => {
+      Object.keys(functionDocs).forEach((name) => delete functionDocs[name]);
+      Object.assign(functionDocs, docs);
+    });
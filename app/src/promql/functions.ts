@@ -0,0 +1,35 @@
+import { Func } from './ast';
+
+// The PromQL function signatures supported by the parser of the PromLens backend.
+// They are loaded from the /api/functions endpoint (see loadFunctionSignatures())
+// before the UI is rendered.
+export const functionSignatures: Record<string, Func> = {};
+
+export const setFunctionSignatures = (fns: Func[]): void => {
+  Object.keys(functionSignatures).forEach((name) => delete functionSignatures[name]);
+  fns.forEach((fn) => {
+    functionSignatures[fn.name] = fn;
+  });
+};
+
+interface APIFunction extends Func {
+  experimental: boolean;
+}
+
+// Loads the function signatures from the backend. Experimental functions are only offered
+// if they are enabled for all users via the backend's parser features.
+export const loadFunctionSignatures = (pathPrefix: string, enableExperimental: boolean): Promise<void> =>
+  fetch(`${pathPrefix}/api/functions`)
+    .then((res) => {
+      if (res.status !== 200) {
+        throw new Error(`Loading function signatures failed: ${res.statusText}`);
+      }
+      return res.json();
+    })
+    .then((fns: APIFunction[]) =>
+      setFunctionSignatures(
+        fns
+          .filter((fn) => enableExperimental || !fn.experimental)
+          .map(({ name, argTypes, variadic, returnType }) => ({ name, argTypes, variadic, returnType }))
+      )
+    );
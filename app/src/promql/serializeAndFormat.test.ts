@@ -7,10 +7,13 @@ import ASTNode, {
   binaryOperatorType,
   vectorMatchCardinality,
 } from './ast';
-import { functionSignatures } from './functionSignatures';
+import { functionSignatures, setFunctionSignatures } from './functions';
+import { testFunctionSignatures } from './testFunctionSignatures';
 import { formatNode } from './format';
 import ReactDOM from 'react-dom';
 
+setFunctionSignatures(testFunctionSignatures);
+
 describe('serializeNode and formatNode', () => {
   it('should serialize correctly', () => {
     const tests: { node: ASTNode; output: string; prettyOutput?: string }[] = [
@@ -0,0 +1,23 @@
+import { valueType, Func } from './ast';
+
+// Function signatures for tests, which don't load them from the backend.
+export const testFunctionSignatures: Func[] = [
+  { name: 'abs', argTypes: [valueType.vector], variadic: 0, returnType: valueType.vector },
+  { name: 'count_over_time', argTypes: [valueType.matrix], variadic: 0, returnType: valueType.vector },
+  { name: 'deriv', argTypes: [valueType.matrix], variadic: 0, returnType: valueType.vector },
+  {
+    name: 'histogram_quantile',
+    argTypes: [valueType.scalar, valueType.vector],
+    variadic: 0,
+    returnType: valueType.vector,
+  },
+  {
+    name: 'label_join',
+    argTypes: [valueType.vector, valueType.string, valueType.string, valueType.string],
+    variadic: -1,
+    returnType: valueType.vector,
+  },
+  { name: 'rate', argTypes: [valueType.matrix], variadic: 0, returnType: valueType.vector },
+  { name: 'scalar', argTypes: [valueType.vector], variadic: 0, returnType: valueType.scalar },
+  { name: 'time', argTypes: [], variadic: 0, returnType: valueType.scalar },
+];
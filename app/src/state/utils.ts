@@ -14,5 +14,5 @@ export const grafanaDatasourceToServerSettings = (ds: GrafanaDataSourceSettings)
   url: ds.url,
   access: ds.access,
   datasourceID: ds.id,
-  withCredentials: ds.withCredentials ? ds.withCredentials : false,
+  withCredentials: ds.withCredentials,
 });
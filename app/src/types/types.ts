@@ -10,9 +10,13 @@ export interface PageConfig {
   license: License | null;
   now: number;
   grafanaDatasources: GrafanaDataSourceSettings[];
+  grafanaDatasourceStatus: GrafanaDatasourceStatus | null;
+  grafanaOrgs: GrafanaOrg[];
+  prometheusServers: PrometheusServer[];
   pageState: ExportedStateV1 | ExportedStateV2orV3 | null;
   defaultPrometheusURL: string;
   defaultGrafanaDatasourceID: number;
+  parserFeatures: string[];
 }
 
 export interface PathPrefixProps {
@@ -25,19 +29,40 @@ export interface Metric {
 
 export type MetricMetadata = Record<string, { type: string; help: string; unit: string }[]>;
 
+export interface PrometheusServer {
+  name: string;
+  url: string;
+  description: string;
+  isDefault: boolean;
+}
+
+export interface GrafanaDatasourceStatus {
+  lastUpdate: string;
+  stale: boolean;
+  error?: string;
+}
+
+export interface GrafanaOrg {
+  id: number;
+  name: string;
+}
+
 export interface GrafanaDataSourceSettings {
   id: number;
   orgID: number;
   name: string;
-  type: string;
   access: 'proxy' | 'direct';
   url: string;
-  password: string;
-  user: string;
-  basicAuth: boolean;
-  basicAuthUser?: string;
-  basicAuthPassword?: string;
-  withCredentials?: boolean;
   isDefault: boolean;
-  jsonData: Record<string, string>;
+  withCredentials: boolean;
+  basicAuth: boolean;
+  customHeaders: boolean;
+  health: GrafanaDatasourceHealth | null;
+}
+
+export interface GrafanaDatasourceHealth {
+  up: boolean;
+  version?: string;
+  error?: string;
+  lastCheck: string;
 }
@@ -20,17 +20,26 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/promslog"
 	promslogflag "github.com/prometheus/common/promslog/flag"
 	"github.com/prometheus/common/version"
 	"github.com/prometheus/exporter-toolkit/web/kingpinflag"
 
+	"github.com/prometheus/promlens/pkg/audit"
+	"github.com/prometheus/promlens/pkg/config"
 	"github.com/prometheus/promlens/pkg/grafana"
+	"github.com/prometheus/promlens/pkg/parser"
+	"github.com/prometheus/promlens/pkg/promproxy"
+	"github.com/prometheus/promlens/pkg/rules"
 	"github.com/prometheus/promlens/pkg/sharer"
 	"github.com/prometheus/promlens/pkg/web"
 )
@@ -78,26 +87,41 @@ func computeExternalURL(u string, listenAddrs []string) (*url.URL, error) {
 	return eu, nil
 }
 
-func getLinkSharer(logger *slog.Logger, gcsBucket string, sqlDriver string, sqlDSN string, createTables bool, sqlRetention time.Duration) (sharer.Sharer, error) {
-	if sqlDSN == "" && gcsBucket == "" {
+// sharedLinksFlagConfig converts the link sharing flags into a configuration section.
+func sharedLinksFlagConfig(gcsBucket string, sqlDriver string, sqlDSN string, createTables bool, sqlRetention time.Duration) (*config.SharedLinksConfig, error) {
+	switch {
+	case sqlDSN == "" && gcsBucket == "":
 		return nil, nil
-	}
-
-	if sqlDSN != "" && gcsBucket != "" {
+	case sqlDSN != "" && gcsBucket != "":
 		return nil, errors.New("multiple link sharing backends are configured - please specify only one")
+	case sqlDSN != "":
+		return &config.SharedLinksConfig{SQL: &config.SQLConfig{
+			Driver:       sqlDriver,
+			DSN:          config_util.Secret(sqlDSN),
+			CreateTables: createTables,
+			Retention:    model.Duration(sqlRetention),
+		}}, nil
 	}
+	return &config.SharedLinksConfig{GCS: &config.GCSConfig{Bucket: gcsBucket}}, nil
+}
 
-	if sqlDriver == "sqlite3" {
-		sqlDriver = "sqlite"
-		logger.Warn("The 'sqlite3' driver is deprecated, using 'sqlite' as a replacement.")
+func getLinkSharer(logger *slog.Logger, cfg *config.SharedLinksConfig) (sharer.Sharer, error) {
+	if cfg == nil {
+		return nil, nil
 	}
 
-	if sqlDSN != "" {
-		if sqlDriver != "mysql" && sqlDriver != "sqlite" && sqlDriver != "postgres" {
-			return nil, fmt.Errorf("unsupported SQL driver %q, supported values are 'mysql', 'postgres' and 'sqlite'", sqlDriver)
+	if sql := cfg.SQL; sql != nil {
+		driver := sql.Driver
+		if driver == "sqlite3" {
+			driver = "sqlite"
+			logger.Warn("The 'sqlite3' driver is deprecated, using 'sqlite' as a replacement.")
+		}
+
+		if driver != "mysql" && driver != "sqlite" && driver != "postgres" {
+			return nil, fmt.Errorf("unsupported SQL driver %q, supported values are 'mysql', 'postgres' and 'sqlite'", driver)
 		}
 
-		s, err := sharer.NewSQLSharer(logger, sqlDriver, sqlDSN, createTables, sqlRetention)
+		s, err := sharer.NewSQLSharer(logger, driver, string(sql.DSN), sql.CreateTables, time.Duration(sql.Retention))
 		if err != nil {
 			return nil, fmt.Errorf("error creating SQL link sharer: %w", err)
 		}
@@ -105,14 +129,15 @@ func getLinkSharer(logger *slog.Logger, gcsBucket string, sqlDriver string, sqlD
 		return s, nil
 	}
 
-	s, err := sharer.NewGCSSharer(gcsBucket)
+	s, err := sharer.NewGCSSharer(cfg.GCS.Bucket)
 	if err != nil {
 		return nil, fmt.Errorf("error creating GCS link sharer: %w", err)
 	}
 	return s, nil
 }
 
-func getGrafanaBackend(url string, token string, tokenFile string) (*grafana.Backend, error) {
+// grafanaFlagConfig converts the Grafana flags into a configuration section.
+func grafanaFlagConfig(url string, token string, tokenFile string, defaultDatasourceID int64, datasourceCacheTTL, healthCheckInterval time.Duration) (*config.GrafanaConfig, error) {
 	if url == "" {
 		return nil, nil
 	}
@@ -125,19 +150,60 @@ func getGrafanaBackend(url string, token string, tokenFile string) (*grafana.Bac
 		return nil, errors.New("can't specify both --grafana.api-token and --grafana.api-token-file - please specify only one")
 	}
 
-	if tokenFile != "" {
-		tokenBuf, err := os.ReadFile(tokenFile)
-		if err != nil {
-			return nil, fmt.Errorf("error reading Grafana API token file %q: %w", tokenFile, err)
-		}
-		token = strings.TrimSpace(string(tokenBuf))
+	return &config.GrafanaConfig{
+		URL:                           url,
+		APIToken:                      config_util.Secret(token),
+		APITokenFile:                  tokenFile,
+		DefaultDatasourceID:           defaultDatasourceID,
+		DatasourceCacheTTL:            model.Duration(datasourceCacheTTL),
+		DatasourceHealthCheckInterval: model.Duration(healthCheckInterval),
+	}, nil
+}
+
+func readGrafanaToken(token config_util.Secret, tokenFile string) (string, error) {
+	if tokenFile == "" {
+		return string(token), nil
+	}
+	tokenBuf, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading Grafana API token file %q: %w", tokenFile, err)
+	}
+	return strings.TrimSpace(string(tokenBuf)), nil
+}
+
+// grafanaOptions returns the options of the Grafana backend, reading the API
+// tokens from their files. It returns nil if Grafana is not configured.
+func grafanaOptions(logger *slog.Logger, cfg *config.GrafanaConfig) (*grafana.Options, error) {
+	if cfg == nil {
+		return nil, nil
 	}
 
-	gb, err := grafana.NewBackend(url, token)
+	token, err := readGrafanaToken(cfg.APIToken, cfg.APITokenFile)
 	if err != nil {
 		return nil, err
 	}
-	return gb, nil
+	orgs := make([]grafana.OrgOptions, 0, len(cfg.Orgs))
+	for _, o := range cfg.Orgs {
+		orgToken, err := readGrafanaToken(o.APIToken, o.APITokenFile)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, grafana.OrgOptions{ID: o.ID, AuthToken: orgToken, DashboardFolderUID: o.DashboardFolderUID})
+	}
+
+	return &grafana.Options{
+		Logger:              logger,
+		URL:                 cfg.URL,
+		AuthToken:           token,
+		UserAuth:            cfg.UserAuth,
+		DatasourceCacheTTL:  time.Duration(cfg.DatasourceCacheTTL),
+		HealthCheckInterval: time.Duration(cfg.DatasourceHealthCheckInterval),
+		DatasourceTypes:     cfg.DatasourceTypes,
+		Orgs:                orgs,
+		DashboardFolderUID:  cfg.DashboardFolderUID,
+		DashboardImport:     cfg.EnableDashboardImport,
+		DashboardExport:     cfg.EnableDashboardExport,
+	}, nil
 }
 
 func main() {
@@ -155,12 +221,22 @@ func main() {
 	grafanaToken := app.Flag("grafana.api-token", "The auth token to pass to the Grafana API.").Default("").String()
 	grafanaTokenFile := app.Flag("grafana.api-token-file", "A file containing the auth token to pass to the Grafana API.").Default("").String()
 	grafanaDefaultDatasourceID := app.Flag("grafana.default-datasource-id", "The default Grafana datasource ID to use (overrides Grafana's own default).").Default("0").Int64()
+	grafanaDatasourceCacheTTL := app.Flag("grafana.datasource-cache-ttl", "The interval at which the list of Grafana datasources is refreshed in the background. If Grafana is unavailable, the last fetched list is used.").Default(config.DefaultDatasourceCacheTTL.String()).Duration()
+	grafanaHealthCheckInterval := app.Flag("grafana.datasource-health-check-interval", "The interval at which the Grafana datasources are probed through the Grafana datasource proxy. Set to 0 to disable health checks.").Default(config.DefaultDatasourceHealthCheckInterval.String()).Duration()
 
 	promlensURL := app.Flag("web.external-url", "The URL under which PromLens is externally reachable (for example, if PromLens is served via a reverse proxy). Used for generating relative and absolute links back to PromLens itself. If the URL has a path portion, it will be used to prefix all HTTP endpoints served by PromLens. If omitted, relevant URL components will be derived automatically.").Default("").String()
 	routePrefix := app.Flag("web.route-prefix", "Prefix for the internal routes of web endpoints. Defaults to path of --web.external-url.").Default("").String()
 
+	enableLifecycle := app.Flag("web.enable-lifecycle", "Enable reloading the configuration via HTTP requests to the /-/reload endpoint.").Default("false").Bool()
+
 	defaultPrometheusURL := app.Flag("web.default-prometheus-url", "The default Prometheus URL to load PromLens with.").Default("").String()
 
+	configFile := app.Flag("config.file", "PromLens configuration file path. Settings in the file take precedence over the corresponding command-line flags. The file is reloaded on SIGHUP, or on a POST or PUT request to the /-/reload endpoint if --web.enable-lifecycle is set.").Default("").String()
+
+	ruleFiles := app.Flag("rules.files", "Prometheus rule files to load, for listing their rules and expanding recording rules in the tree view. Supports file globs (e.g. 'rules/*.yml'). Can be repeated.").Strings()
+
+	parserFeatures := app.Flag("parser.enable-feature", "Comma-separated list of experimental PromQL parser features to enable for all parse requests (individual requests may enable further features). Valid options: "+strings.Join(parser.AvailableFeatures, ", ")+".").Default("").Strings()
+
 	var logCfg promslog.Config
 	promslogflag.AddFlags(app, &logCfg)
 
@@ -188,41 +264,90 @@ func main() {
 	// RoutePrefix must always be at least '/'.
 	*routePrefix = "/" + strings.Trim(*routePrefix, "/")
 
-	// Initialize link sharer.
+	features, err := parser.ParseFeatures(*parserFeatures)
+	if err != nil {
+		logger.Error("Error parsing enabled parser features.", "err", err)
+		os.Exit(2)
+	}
+
+	// Initialize the reloadable components from the flags and the config file.
 	if *sharedLinksSQLDSN == "" && os.Getenv("PROMLENS_SHARED_LINKS_DSN") != "" {
 		*sharedLinksSQLDSN = os.Getenv("PROMLENS_SHARED_LINKS_DSN")
 	}
-	shr, err := getLinkSharer(logger, *sharedLinksGCSBucket, *sharedLinksSQLDriver, *sharedLinksSQLDSN, *createSharedLinksTables, *sharedLinksRetention)
+	sharedLinksCfg, err := sharedLinksFlagConfig(*sharedLinksGCSBucket, *sharedLinksSQLDriver, *sharedLinksSQLDSN, *createSharedLinksTables, *sharedLinksRetention)
 	if err != nil {
 		logger.Error("Error initializing link sharer.", "err", err)
 		os.Exit(2)
 	}
-	if shr == nil {
-		logger.Info("No link sharing backends are enabled - disabling link sharing functionality.")
-	} else {
-		defer func() {
-			logger.Info("Closing link sharer.")
-			shr.Close()
-		}()
-	}
-
-	gb, err := getGrafanaBackend(*grafanaURL, *grafanaToken, *grafanaTokenFile)
+	grafanaCfg, err := grafanaFlagConfig(*grafanaURL, *grafanaToken, *grafanaTokenFile, *grafanaDefaultDatasourceID, *grafanaDatasourceCacheTTL, *grafanaHealthCheckInterval)
 	if err != nil {
 		logger.Error("Error initializing Grafana backend.", "err", err)
 		os.Exit(2)
 	}
-	if gb == nil {
-		logger.Info("No Grafana backend enabled - disabling Grafana datasource integration.")
+
+	rl := &reloader{
+		logger:      logger,
+		configFile:  *configFile,
+		externalURL: externalURL,
+		flagConfig: config.Config{
+			Grafana:     grafanaCfg,
+			SharedLinks: sharedLinksCfg,
+			UI:          &config.UIConfig{DefaultPrometheusURL: *defaultPrometheusURL},
+		},
+		promProxy:   promproxy.New(logger),
+		auditLogger: audit.New(),
+	}
+	if err := rl.reload(); err != nil {
+		logger.Error("Error loading configuration.", "err", err)
+		os.Exit(2)
+	}
+	defer rl.close()
+
+	var ruleManager *rules.Manager
+	if len(*ruleFiles) > 0 {
+		ruleManager, err = rules.NewManager(logger, *ruleFiles, func(expr string) error {
+			_, err := parser.ParseExpr(expr, features)
+			return err
+		})
+		if err != nil {
+			logger.Error("Error loading rule files.", "err", err)
+			os.Exit(2)
+		}
+	}
+
+	reload := func() error {
+		err := rl.reload()
+		if ruleManager != nil {
+			err = errors.Join(err, ruleManager.Reload())
+		}
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reload(); err != nil {
+				logger.Error("Error reloading configuration.", "err", err)
+			}
+		}
+	}()
+
+	var httpReload func() error
+	if *enableLifecycle {
+		httpReload = reload
 	}
 
 	logger.Error("Running HTTP server failed.", "err", web.Serve(&web.Config{
-		Logger:                     logger,
-		ToolkitConfig:              toolkitConfig,
-		RoutePrefix:                *routePrefix,
-		ExternalURL:                externalURL,
-		Sharer:                     shr,
-		GrafanaBackend:             gb,
-		DefaultPrometheusURL:       strings.TrimRight(*defaultPrometheusURL, "/"),
-		DefaultGrafanaDatasourceID: *grafanaDefaultDatasourceID,
+		Logger:          logger,
+		ToolkitConfig:   toolkitConfig,
+		RoutePrefix:     *routePrefix,
+		ExternalURL:     externalURL,
+		PrometheusProxy: rl.promProxy,
+		AuditLogger:     rl.auditLogger,
+		ParserFeatures:  features,
+		RuleManager:     ruleManager,
+		Components:      rl.components,
+		Reload:          httpReload,
 	}))
 }
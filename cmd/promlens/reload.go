@@ -0,0 +1,261 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/prometheus/promlens/pkg/audit"
+	"github.com/prometheus/promlens/pkg/auth"
+	"github.com/prometheus/promlens/pkg/config"
+	"github.com/prometheus/promlens/pkg/grafana"
+	"github.com/prometheus/promlens/pkg/pageconfig"
+	"github.com/prometheus/promlens/pkg/promproxy"
+	"github.com/prometheus/promlens/pkg/web"
+)
+
+var (
+	configSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "promlens_config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful.",
+	})
+	configSuccessTime = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "promlens_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(configSuccess, configSuccessTime)
+}
+
+// reloader builds the reloadable components of PromLens from the command-line
+// flags and the configuration file, and swaps them atomically on reload.
+type reloader struct {
+	logger      *slog.Logger
+	configFile  string
+	flagConfig  config.Config
+	externalURL *url.URL
+	promProxy   *promproxy.Proxy
+	auditLogger *audit.Logger
+
+	// reloadMtx serializes reloads and protects cfg and grafanaOpts.
+	reloadMtx   sync.Mutex
+	cfg         config.Config
+	grafanaOpts *grafana.Options
+
+	mtx sync.RWMutex
+	gen *generation
+}
+
+// generation is a set of components along with the requests that use them,
+// so that replaced components are only closed once those requests are done.
+type generation struct {
+	comps    web.Components
+	requests sync.WaitGroup
+}
+
+// components returns the active components and a function to call once the
+// caller is done using them.
+func (r *reloader) components() (web.Components, func()) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	g := r.gen
+	g.requests.Add(1)
+	return g.comps, g.requests.Done
+}
+
+// reload loads the configuration file (if any) and replaces the components
+// whose configuration has changed. All components are built and validated
+// before any of them is applied, so if anything fails, the previous
+// components are kept.
+func (r *reloader) reload() (err error) {
+	r.reloadMtx.Lock()
+	defer r.reloadMtx.Unlock()
+
+	defer func() {
+		if err == nil {
+			configSuccess.Set(1)
+			configSuccessTime.SetToCurrentTime()
+		} else {
+			configSuccess.Set(0)
+		}
+	}()
+
+	cfg := r.flagConfig
+	if r.configFile != "" {
+		fileCfg, err := config.LoadFile(r.configFile)
+		if err != nil {
+			return fmt.Errorf("error loading config file: %w", err)
+		}
+		cfg = fileCfg.Merge(r.flagConfig)
+	}
+
+	var old web.Components
+	if r.gen != nil {
+		old = r.gen.comps
+	}
+	comps := web.Components{Sharer: old.Sharer, GrafanaBackend: old.GrafanaBackend}
+
+	grafanaOpts, err := grafanaOptions(r.logger, cfg.Grafana)
+	if err != nil {
+		return fmt.Errorf("error initializing Grafana backend: %w", err)
+	}
+	// Only recreate the link sharer when its configuration changes, since it
+	// may hold database connections, and the Grafana backend when its options
+	// change, so that it keeps its cached datasources and health checks.
+	sharerChanged := r.gen == nil || !reflect.DeepEqual(cfg.SharedLinks, r.cfg.SharedLinks)
+	grafanaChanged := r.gen == nil || !reflect.DeepEqual(grafanaOpts, r.grafanaOpts)
+	if sharerChanged {
+		comps.Sharer = nil
+	}
+	if grafanaChanged {
+		comps.GrafanaBackend = nil
+	}
+	// closeNew releases the new components if the reload fails.
+	closeNew := func() {
+		if sharerChanged && comps.Sharer != nil {
+			comps.Sharer.Close()
+		}
+		if grafanaChanged && comps.GrafanaBackend != nil {
+			comps.GrafanaBackend.Close()
+		}
+	}
+
+	if sharerChanged {
+		if comps.Sharer, err = getLinkSharer(r.logger, cfg.SharedLinks); err != nil {
+			return fmt.Errorf("error initializing link sharer: %w", err)
+		}
+		if comps.Sharer == nil {
+			r.logger.Info("No link sharing backends are enabled - disabling link sharing functionality.")
+		}
+	}
+	if grafanaChanged && grafanaOpts != nil {
+		if comps.GrafanaBackend, err = grafana.NewBackend(*grafanaOpts); err != nil {
+			closeNew()
+			return fmt.Errorf("error initializing Grafana backend: %w", err)
+		}
+	}
+	if comps.GrafanaBackend == nil {
+		r.logger.Info("No Grafana backend enabled - disabling Grafana datasource integration.")
+	} else {
+		comps.DefaultGrafanaDatasourceID = cfg.Grafana.DefaultDatasourceID
+	}
+
+	if cfg.Auth != nil {
+		if comps.Authenticator, err = auth.New(r.logger, cfg.Auth, r.externalURL); err != nil {
+			closeNew()
+			return fmt.Errorf("error initializing authentication: %w", err)
+		}
+	}
+
+	promServers, err := r.promProxy.NewServers(cfg.PrometheusServers)
+	if err != nil {
+		closeNew()
+		return fmt.Errorf("error initializing Prometheus proxy: %w", err)
+	}
+
+	// The audit log is reopened on every reload to support log rotation.
+	auditOutput, err := audit.Open(cfg.AuditLog)
+	if err != nil {
+		closeNew()
+		promServers.Close()
+		return fmt.Errorf("error initializing audit log: %w", err)
+	}
+
+	if cfg.UI != nil {
+		comps.DefaultPrometheusURL = strings.TrimRight(cfg.UI.DefaultPrometheusURL, "/")
+	}
+	comps.PrometheusServers = make([]pageconfig.PrometheusServer, 0, len(cfg.PrometheusServers))
+	for _, sc := range cfg.PrometheusServers {
+		ps := pageconfig.PrometheusServer{
+			Name:        sc.Name,
+			URL:         strings.TrimRight(sc.URL.String(), "/"),
+			Description: sc.Description,
+		}
+		if sc.Access == promproxy.AccessProxy {
+			ps.URL = r.externalURL.Path + promproxy.Path(sc.Name)
+		}
+		if cfg.UI != nil && cfg.UI.DefaultPrometheusServer == sc.Name {
+			ps.IsDefault = true
+			comps.DefaultPrometheusURL = ps.URL
+		}
+		comps.PrometheusServers = append(comps.PrometheusServers, ps)
+	}
+
+	// Everything is valid, so apply all changes.
+	r.promProxy.SetServers(promServers).Close()
+	if err := r.auditLogger.SetOutput(auditOutput); err != nil {
+		r.logger.Error("Error closing previous audit log file.", "err", err)
+	}
+	r.mtx.Lock()
+	oldGen := r.gen
+	r.gen = &generation{comps: comps}
+	r.mtx.Unlock()
+	r.cfg = cfg
+	r.grafanaOpts = grafanaOpts
+
+	if oldGen != nil {
+		r.retire(oldGen, sharerChanged, grafanaChanged)
+	}
+	if r.configFile != "" {
+		r.logger.Info("Completed loading of configuration file.", "filename", r.configFile)
+	}
+	return nil
+}
+
+// retire closes the replaced components of a previous generation once all
+// requests that use them are done.
+func (r *reloader) retire(g *generation, sharerChanged, grafanaChanged bool) {
+	closeSharer := sharerChanged && g.comps.Sharer != nil
+	closeGrafana := grafanaChanged && g.comps.GrafanaBackend != nil
+	if !closeSharer && !closeGrafana {
+		return
+	}
+	go func() {
+		// No requests are added to the generation after it was replaced.
+		g.requests.Wait()
+		if closeSharer {
+			r.logger.Info("Closing previous link sharer.")
+			g.comps.Sharer.Close()
+		}
+		if closeGrafana {
+			g.comps.GrafanaBackend.Close()
+		}
+	}()
+}
+
+// close releases the resources of the active components.
+func (r *reloader) close() {
+	r.mtx.RLock()
+	comps := r.gen.comps
+	r.mtx.RUnlock()
+	if comps.Sharer != nil {
+		r.logger.Info("Closing link sharer.")
+		comps.Sharer.Close()
+	}
+	if comps.GrafanaBackend != nil {
+		comps.GrafanaBackend.Close()
+	}
+	if err := r.auditLogger.Close(); err != nil {
+		r.logger.Error("Error closing audit log.", "err", err)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/promlens/pkg/audit"
+	"github.com/prometheus/promlens/pkg/promproxy"
+)
+
+func newTestReloader(t *testing.T, cfg string) (*reloader, string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "promlens.yml")
+	if err := os.WriteFile(file, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.DiscardHandler)
+	r := &reloader{
+		logger:      logger,
+		configFile:  file,
+		externalURL: &url.URL{Scheme: "http", Host: "localhost:8080"},
+		promProxy:   promproxy.New(logger),
+		auditLogger: audit.New(),
+	}
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.close)
+	return r, file
+}
+
+func writeConfig(t *testing.T, file, cfg string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// proxyStatus returns the status of a proxied request to the named server.
+func proxyStatus(r *reloader, name string) int {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/prometheus/"+name+"/api/v1/foo", nil)
+	r.promProxy.Handle("", nil)(rec, req)
+	return rec.Code
+}
+
+func TestReloadIsAtomic(t *testing.T) {
+	r, file := newTestReloader(t, `
+prometheus_servers:
+  - name: old
+    url: http://old.example.com
+`)
+	if got := proxyStatus(r, "old"); got != http.StatusForbidden {
+		t.Fatalf("expected old server to be proxied, got status %d", got)
+	}
+
+	// The audit log can't be opened, so the new servers must not be applied.
+	writeConfig(t, file, `
+prometheus_servers:
+  - name: new
+    url: http://new.example.com
+audit_log:
+  file: `+filepath.Join(t.TempDir(), "missing", "audit.log")+`
+`)
+	if err := r.reload(); err == nil {
+		t.Fatal("expected reload to fail")
+	}
+	if got := proxyStatus(r, "old"); got != http.StatusForbidden {
+		t.Errorf("expected old server to still be proxied, got status %d", got)
+	}
+	if got := proxyStatus(r, "new"); got != http.StatusNotFound {
+		t.Errorf("expected new server not to be proxied, got status %d", got)
+	}
+	comps, done := r.components()
+	defer done()
+	if len(comps.PrometheusServers) != 1 || comps.PrometheusServers[0].Name != "old" {
+		t.Errorf("unexpected Prometheus servers after failed reload: %+v", comps.PrometheusServers)
+	}
+}
+
+func TestReloadKeepsGrafanaBackend(t *testing.T) {
+	const grafanaCfg = `
+grafana:
+  url: http://grafana.example.com
+  api_token: secret
+  datasource_health_check_interval: 0s
+`
+	r, file := newTestReloader(t, grafanaCfg)
+	comps, done := r.components()
+	done()
+	first := comps.GrafanaBackend
+	if first == nil {
+		t.Fatal("expected Grafana backend")
+	}
+
+	writeConfig(t, file, grafanaCfg+"  default_datasource_id: 2\n")
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	comps, done = r.components()
+	done()
+	if comps.GrafanaBackend != first {
+		t.Error("expected Grafana backend to be kept when its options are unchanged")
+	}
+	if comps.DefaultGrafanaDatasourceID != 2 {
+		t.Errorf("expected default datasource 2, got %d", comps.DefaultGrafanaDatasourceID)
+	}
+
+	writeConfig(t, file, grafanaCfg+"  datasource_cache_ttl: 5m\n")
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	comps, done = r.components()
+	done()
+	if comps.GrafanaBackend == first {
+		t.Error("expected Grafana backend to be recreated when its options change")
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit writes a log of who ran which queries and who created and
+// loaded which shared links.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/config"
+
+	"github.com/prometheus/promlens/pkg/auth"
+)
+
+// Audited actions.
+const (
+	// ActionProxy is a request that is proxied to a Prometheus server or a
+	// Grafana datasource.
+	ActionProxy      = "proxy"
+	ActionCreateLink = "create_link"
+	ActionLoadLink   = "load_link"
+	// ActionExportDashboard is logged when queries are exported to a Grafana
+	// dashboard.
+	ActionExportDashboard = "export_dashboard"
+)
+
+// maxFormSize is the maximum size of a form body from which the query is
+// extracted.
+const maxFormSize = 10 << 20
+
+var writeErrors = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "promlens_audit_log_write_errors_total",
+	Help: "The total number of audit events that could not be written.",
+})
+
+func init() {
+	prometheus.MustRegister(writeErrors)
+}
+
+// Config configures the audit log.
+type Config struct {
+	// File is the file to append the audit log to. If empty, the audit log is
+	// written to standard output.
+	File string `yaml:"file,omitempty"`
+}
+
+// SetDirectory joins any relative file paths with dir.
+func (c *Config) SetDirectory(dir string) {
+	c.File = config.JoinDir(dir, c.File)
+}
+
+// Event is a single audit log entry.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	// User and AuthMethod are only set if authentication is enabled.
+	User       string `json:"user,omitempty"`
+	AuthMethod string `json:"authMethod,omitempty"`
+	ClientIP   string `json:"clientIP"`
+	// ForwardedFor is the client's X-Forwarded-For header, if any.
+	ForwardedFor     string `json:"forwardedFor,omitempty"`
+	DatasourceID     int64  `json:"datasourceID,omitempty"`
+	DatasourceUID    string `json:"datasourceUID,omitempty"`
+	PrometheusServer string `json:"prometheusServer,omitempty"`
+	// Path is the proxied API path.
+	Path  string `json:"path,omitempty"`
+	Query string `json:"query,omitempty"`
+	Link  string `json:"link,omitempty"`
+	// Dashboard is the UID of an exported Grafana dashboard.
+	Dashboard string `json:"dashboard,omitempty"`
+}
+
+// Logger writes audit events as JSON lines. It is disabled until a
+// configuration is applied. A nil Logger discards all events.
+type Logger struct {
+	mtx sync.Mutex
+	w   io.Writer
+	f   *os.File
+}
+
+// New creates a disabled Logger.
+func New() *Logger {
+	return &Logger{}
+}
+
+// Output is an opened audit log destination. It is created by Open and
+// activated by Logger.SetOutput.
+type Output struct {
+	w io.Writer
+	f *os.File
+}
+
+// Open opens the audit log as configured. If cfg is nil, it returns a nil
+// Output, which disables the audit log.
+func Open(cfg *Config) (*Output, error) {
+	switch {
+	case cfg == nil:
+		return nil, nil
+	case cfg.File == "":
+		return &Output{w: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log file: %w", err)
+	}
+	return &Output{w: f, f: f}, nil
+}
+
+// Close closes an Output that was never set on a Logger.
+func (o *Output) Close() error {
+	if o == nil || o.f == nil {
+		return nil
+	}
+	return o.f.Close()
+}
+
+// SetOutput replaces the destination of the audit log and closes the previous
+// file. A nil Output disables the audit log.
+func (l *Logger) SetOutput(o *Output) error {
+	var (
+		w io.Writer
+		f *os.File
+	)
+	if o != nil {
+		w, f = o.w, o.f
+	}
+
+	l.mtx.Lock()
+	old := l.f
+	l.w, l.f = w, f
+	l.mtx.Unlock()
+
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// ApplyConfig (re)opens the audit log as configured, or disables it if cfg is
+// nil. Since the file is reopened on every call, reloading the configuration
+// also works for rotating the audit log.
+func (l *Logger) ApplyConfig(cfg *Config) error {
+	o, err := Open(cfg)
+	if err != nil {
+		return err
+	}
+	return l.SetOutput(o)
+}
+
+// Close closes the audit log file.
+func (l *Logger) Close() error {
+	return l.ApplyConfig(nil)
+}
+
+// Enabled returns whether events are logged.
+func (l *Logger) Enabled() bool {
+	if l == nil {
+		return false
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.w != nil
+}
+
+// Log logs an event that was caused by the request r. The time, user, and
+// client address are taken from the request.
+func (l *Logger) Log(r *http.Request, e Event) {
+	if !l.Enabled() {
+		return
+	}
+
+	e.Time = time.Now().UTC()
+	if id, ok := auth.FromContext(r.Context()); ok {
+		e.User = id.User
+		e.AuthMethod = id.Method
+	}
+	e.ClientIP = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		e.ClientIP = host
+	}
+	e.ForwardedFor = r.Header.Get("X-Forwarded-For")
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		writeErrors.Inc()
+		return
+	}
+	line = append(line, '\n')
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.w == nil {
+		return
+	}
+	if _, err := l.w.Write(line); err != nil {
+		writeErrors.Inc()
+	}
+}
+
+// Query returns the PromQL query of a Prometheus API request from its "query"
+// URL parameter or form body parameter. The request body is left intact, so
+// that the request can still be proxied.
+func Query(r *http.Request) string {
+	if q := r.URL.Query().Get("query"); q != "" {
+		return q
+	}
+	if r.Method != http.MethodPost || r.Body == nil {
+		return ""
+	}
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/x-www-form-urlencoded" {
+		return ""
+	}
+
+	orig := r.Body
+	body, err := io.ReadAll(io.LimitReader(orig, maxFormSize))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), orig), orig}
+	if err != nil || len(body) == maxFormSize {
+		return ""
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return form.Get("query")
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/promlens/pkg/auth"
+)
+
+func TestQuery(t *testing.T) {
+	form := url.Values{"query": {"sum(rate(x[5m]))"}, "time": {"1"}}.Encode()
+	largeForm := "query=up&padding=" + strings.Repeat("a", maxFormSize)
+
+	for _, tc := range []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:   "GET query parameter",
+			method: http.MethodGet,
+			target: "/api/v1/query?query=up",
+			want:   "up",
+		},
+		{
+			name:   "GET without query",
+			method: http.MethodGet,
+			target: "/api/v1/labels",
+		},
+		{
+			name:        "form-encoded POST",
+			method:      http.MethodPost,
+			target:      "/api/v1/query",
+			contentType: "application/x-www-form-urlencoded",
+			body:        form,
+			want:        "sum(rate(x[5m]))",
+		},
+		{
+			name:        "form-encoded POST with charset",
+			method:      http.MethodPost,
+			target:      "/api/v1/query",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			body:        form,
+			want:        "sum(rate(x[5m]))",
+		},
+		{
+			name:        "URL parameter takes precedence over POST body",
+			method:      http.MethodPost,
+			target:      "/api/v1/query?query=up",
+			contentType: "application/x-www-form-urlencoded",
+			body:        form,
+			want:        "up",
+		},
+		{
+			name:        "non-form POST",
+			method:      http.MethodPost,
+			target:      "/api/v1/query",
+			contentType: "application/json",
+			body:        `{"query": "up"}`,
+		},
+		{
+			name:        "form body with method other than POST",
+			method:      http.MethodPut,
+			target:      "/api/v1/query",
+			contentType: "application/x-www-form-urlencoded",
+			body:        form,
+		},
+		{
+			name:        "invalid form body",
+			method:      http.MethodPost,
+			target:      "/api/v1/query",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "query=%zz",
+		},
+		{
+			name:        "oversized form body",
+			method:      http.MethodPost,
+			target:      "/api/v1/query",
+			contentType: "application/x-www-form-urlencoded",
+			body:        largeForm,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			if got := Query(r); got != tc.want {
+				t.Errorf("got query %q, want %q", got, tc.want)
+			}
+
+			// The body must still be complete, so that the request can be
+			// proxied.
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tc.body {
+				t.Errorf("request body changed: got %d bytes, want %d bytes", len(body), len(tc.body))
+			}
+			if err := r.Body.Close(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	l := New()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+
+	// Events are discarded until an output is set.
+	l.Log(r, Event{Action: ActionProxy})
+	var nilLogger *Logger
+	nilLogger.Log(r, Event{Action: ActionProxy})
+	if l.Enabled() || nilLogger.Enabled() {
+		t.Fatal("expected logger without output to be disabled")
+	}
+
+	if err := l.SetOutput(&Output{w: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		req  func() *http.Request
+		want Event
+	}{
+		{
+			name: "anonymous",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+				r.RemoteAddr = "192.0.2.1:1234"
+				return r
+			},
+			want: Event{Action: ActionProxy, ClientIP: "192.0.2.1", Query: "up"},
+		},
+		{
+			name: "authenticated and forwarded",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+				r.RemoteAddr = "[2001:db8::1]:1234"
+				r.Header.Set("X-Forwarded-For", "198.51.100.7, 10.0.0.1")
+				return r.WithContext(auth.NewContext(r.Context(), &auth.Identity{User: "alice", Method: auth.MethodBasic}))
+			},
+			want: Event{
+				Action:       ActionProxy,
+				User:         "alice",
+				AuthMethod:   auth.MethodBasic,
+				ClientIP:     "2001:db8::1",
+				ForwardedFor: "198.51.100.7, 10.0.0.1",
+				Query:        "up",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			l.Log(tc.req(), Event{Action: ActionProxy, Query: "up"})
+
+			line, err := buf.ReadString('\n')
+			if err != nil {
+				t.Fatalf("expected a complete line, got %q", line)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("expected a single line, got additional output %q", buf.String())
+			}
+			var got Event
+			if err := json.Unmarshal([]byte(line), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Time.IsZero() {
+				t.Error("event has no time")
+			}
+			got.Time = tc.want.Time
+			if got != tc.want {
+				t.Errorf("got event %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "audit.log")
+	l := New()
+	if err := l.ApplyConfig(&Config{File: fn}); err != nil {
+		t.Fatal(err)
+	}
+	l.Log(httptest.NewRequest(http.MethodGet, "/", nil), Event{Action: ActionCreateLink, Link: "abc"})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Events after closing are discarded.
+	l.Log(httptest.NewRequest(http.MethodGet, "/", nil), Event{Action: ActionLoadLink, Link: "abc"})
+
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"action":"create_link"`) {
+		t.Errorf("unexpected audit log contents %q", b)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus/common/config"
+)
+
+// Authentication methods.
+const (
+	MethodHeader = "header"
+	MethodBasic  = "basic"
+	MethodOIDC   = "oidc"
+)
+
+// Identity is an authenticated user.
+type Identity struct {
+	User   string   `json:"user"`
+	Email  string   `json:"email,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	// Method is the method by which the user was authenticated.
+	Method string `json:"method"`
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the identity.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity stored in ctx, if any.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(*Identity)
+	return id, ok
+}
+
+// Config configures the authentication methods. When multiple methods are
+// configured, a request is authenticated by the first method that accepts it,
+// in the order: trusted header, basic auth, OIDC session.
+type Config struct {
+	TrustedHeader  *HeaderConfig            `yaml:"trusted_header,omitempty"`
+	BasicAuthUsers map[string]config.Secret `yaml:"basic_auth_users,omitempty"`
+	OIDC           *OIDCConfig              `yaml:"oidc,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.TrustedHeader == nil && len(c.BasicAuthUsers) == 0 && c.OIDC == nil {
+		return errors.New("at least one authentication method must be configured")
+	}
+	return nil
+}
+
+// SetDirectory joins any relative file paths with dir.
+func (c *Config) SetDirectory(dir string) {
+	if c.OIDC != nil {
+		c.OIDC.SetDirectory(dir)
+	}
+}
+
+// Authenticator authenticates requests with the configured methods.
+type Authenticator struct {
+	logger *slog.Logger
+	header *headerAuth
+	basic  *basicAuth
+	oidc   *oidcAuth
+}
+
+// New creates an Authenticator. The external URL is used to build the OIDC
+// callback URL and to scope the session cookies.
+func New(logger *slog.Logger, cfg *Config, externalURL *url.URL) (*Authenticator, error) {
+	a := &Authenticator{logger: logger}
+	var err error
+	if cfg.TrustedHeader != nil {
+		if a.header, err = newHeaderAuth(cfg.TrustedHeader); err != nil {
+			return nil, fmt.Errorf("error configuring trusted header authentication: %w", err)
+		}
+	}
+	if len(cfg.BasicAuthUsers) > 0 {
+		a.basic = newBasicAuth(cfg.BasicAuthUsers)
+	}
+	if cfg.OIDC != nil {
+		if a.oidc, err = newOIDCAuth(logger, cfg.OIDC, externalURL); err != nil {
+			return nil, fmt.Errorf("error configuring OIDC authentication: %w", err)
+		}
+	}
+	return a, nil
+}
+
+func (a *Authenticator) authenticate(r *http.Request) *Identity {
+	if a.header != nil {
+		if id := a.header.authenticate(r); id != nil {
+			return id
+		}
+	}
+	if a.basic != nil {
+		if id := a.basic.authenticate(r); id != nil {
+			return id
+		}
+	}
+	if a.oidc != nil {
+		if id := a.oidc.authenticate(r); id != nil {
+			return id
+		}
+	}
+	return nil
+}
+
+// challenge asks an unauthenticated client to authenticate: browsers loading
+// a page are sent to the OIDC login, all other requests get a 401.
+func (a *Authenticator) challenge(w http.ResponseWriter, r *http.Request, routePrefix string) {
+	path := strings.TrimPrefix(r.URL.Path, routePrefix)
+	if a.oidc != nil && r.Method == http.MethodGet && !strings.HasPrefix(path, "/api/") {
+		a.oidc.redirectToLogin(w, r, strings.TrimPrefix(r.URL.RequestURI(), routePrefix))
+		return
+	}
+	if a.basic != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="PromLens"`)
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// Handle authenticates all requests to next and stores the identity in the
+// request context. Unauthenticated requests are rejected, except for the OIDC
+// login endpoints below "<routePrefix>/auth/" and the metrics endpoint.
+func (a *Authenticator) Handle(routePrefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, routePrefix)
+		if a.oidc != nil && strings.HasPrefix(path, oidcPathPrefix) {
+			a.oidc.handle(w, r, strings.TrimPrefix(path, oidcPathPrefix))
+			return
+		}
+		if path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := a.authenticate(r)
+		if id == nil {
+			unauthenticatedRequests.Inc()
+			a.challenge(w, r, routePrefix)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
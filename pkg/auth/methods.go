@@ -0,0 +1,194 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var unauthenticatedRequests = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "promlens_auth_unauthenticated_requests_total",
+	Help: "The total number of requests that were rejected because they were not authenticated.",
+})
+
+func init() {
+	prometheus.MustRegister(unauthenticatedRequests)
+}
+
+// HeaderConfig configures authentication via headers that are set by a
+// trusted reverse proxy in front of PromLens.
+type HeaderConfig struct {
+	UserHeader   string `yaml:"user_header"`
+	EmailHeader  string `yaml:"email_header,omitempty"`
+	GroupsHeader string `yaml:"groups_header,omitempty"`
+	// TrustedProxies restricts the accepted headers to requests from these
+	// networks (in CIDR notation), so that clients can't set them directly.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *HeaderConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain HeaderConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.UserHeader == "" {
+		return errors.New("missing user_header for trusted header authentication")
+	}
+	if len(c.TrustedProxies) == 0 {
+		return errors.New("missing trusted_proxies for trusted header authentication")
+	}
+	return nil
+}
+
+type headerAuth struct {
+	cfg     *HeaderConfig
+	proxies []*net.IPNet
+}
+
+func newHeaderAuth(cfg *HeaderConfig) (*headerAuth, error) {
+	h := &headerAuth{cfg: cfg}
+	for _, cidr := range cfg.TrustedProxies {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy network %q: %w", cidr, err)
+		}
+		h.proxies = append(h.proxies, n)
+	}
+	return h, nil
+}
+
+func (h *headerAuth) trusted(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	for _, n := range h.proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *headerAuth) authenticate(r *http.Request) *Identity {
+	user := r.Header.Get(h.cfg.UserHeader)
+	if user == "" || !h.trusted(r) {
+		return nil
+	}
+
+	id := &Identity{User: user, Method: MethodHeader}
+	if h.cfg.EmailHeader != "" {
+		id.Email = r.Header.Get(h.cfg.EmailHeader)
+	}
+	if h.cfg.GroupsHeader != "" {
+		for _, g := range strings.Split(r.Header.Get(h.cfg.GroupsHeader), ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				id.Groups = append(id.Groups, g)
+			}
+		}
+	}
+	return id
+}
+
+// dummyHash is compared against when a user doesn't exist, so that the
+// response time doesn't reveal which users exist. It is only generated when
+// basic auth is used.
+var dummyHash = sync.OnceValue(func() []byte {
+	h, err := bcrypt.GenerateFromPassword([]byte("promlens"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return h
+})
+
+// maxBasicAuthCacheSize limits the number of cached password checks.
+const maxBasicAuthCacheSize = 100
+
+type basicAuth struct {
+	// users maps user names to bcrypt password hashes.
+	users map[string]config.Secret
+
+	// cache holds the results of recent password checks, since bcrypt is
+	// deliberately slow and browsers send the credentials with every request.
+	// The keys are hashes of the user name, password hash, and password.
+	mtx   sync.Mutex
+	cache map[[sha256.Size]byte]bool
+}
+
+func newBasicAuth(users map[string]config.Secret) *basicAuth {
+	return &basicAuth{users: users, cache: map[[sha256.Size]byte]bool{}}
+}
+
+// checkPassword compares the password against the bcrypt hash, reusing the
+// result of an earlier comparison if it is cached.
+func (b *basicAuth) checkPassword(user, hash, pass string) bool {
+	h := sha256.New()
+	for _, s := range []string{user, hash, pass} {
+		// Prefix each part with its length so that they can't be shifted.
+		_ = binary.Write(h, binary.BigEndian, uint64(len(s)))
+		h.Write([]byte(s))
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+
+	b.mtx.Lock()
+	ok, cached := b.cache[key]
+	b.mtx.Unlock()
+	if cached {
+		return ok
+	}
+
+	ok = bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if len(b.cache) >= maxBasicAuthCacheSize {
+		// Evict an arbitrary entry.
+		for k := range b.cache {
+			delete(b.cache, k)
+			break
+		}
+	}
+	b.cache[key] = ok
+	return ok
+}
+
+func (b *basicAuth) authenticate(r *http.Request) *Identity {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+	hash, exists := b.users[user]
+	if !exists {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(pass))
+		return nil
+	}
+	if !b.checkPassword(user, string(hash), pass) {
+		return nil
+	}
+	return &Identity{User: user, Method: MethodBasic}
+}
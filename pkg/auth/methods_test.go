@@ -0,0 +1,132 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/common/config"
+	"go.yaml.in/yaml/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHeaderConfigRequiresTrustedProxies(t *testing.T) {
+	for _, tc := range []struct {
+		config  string
+		wantErr bool
+	}{
+		{config: "user_header: X-User\ntrusted_proxies: [10.0.0.0/8]\n"},
+		{config: "user_header: X-User\n", wantErr: true},
+		{config: "user_header: X-User\ntrusted_proxies: []\n", wantErr: true},
+		{config: "trusted_proxies: [10.0.0.0/8]\n", wantErr: true},
+	} {
+		var c HeaderConfig
+		err := yaml.UnmarshalStrict([]byte(tc.config), &c)
+		if tc.wantErr && err == nil {
+			t.Errorf("%q: expected error, got none", tc.config)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.config, err)
+		}
+	}
+}
+
+func TestHeaderAuth(t *testing.T) {
+	h, err := newHeaderAuth(&HeaderConfig{
+		UserHeader:     "X-User",
+		EmailHeader:    "X-Email",
+		GroupsHeader:   "X-Groups",
+		TrustedProxies: []string{"10.0.0.0/8", "::1/128"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		user       string
+		wantUser   string
+	}{
+		{name: "trusted proxy", remoteAddr: "10.1.2.3:1234", user: "alice", wantUser: "alice"},
+		{name: "trusted IPv6 proxy", remoteAddr: "[::1]:1234", user: "alice", wantUser: "alice"},
+		{name: "untrusted client", remoteAddr: "192.168.0.1:1234", user: "alice"},
+		{name: "missing header", remoteAddr: "10.1.2.3:1234"},
+		{name: "malformed address", remoteAddr: "10.1.2.3", user: "alice"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.user != "" {
+				r.Header.Set("X-User", tc.user)
+			}
+			r.Header.Set("X-Email", "alice@example.com")
+			r.Header.Set("X-Groups", "admins, ,devs")
+			id := h.authenticate(r)
+			if tc.wantUser == "" {
+				if id != nil {
+					t.Fatalf("expected no identity, got %+v", id)
+				}
+				return
+			}
+			if id == nil {
+				t.Fatal("expected identity, got none")
+			}
+			if id.User != tc.wantUser || id.Email != "alice@example.com" || len(id.Groups) != 2 || id.Groups[0] != "admins" || id.Groups[1] != "devs" {
+				t.Errorf("unexpected identity %+v", id)
+			}
+		})
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newBasicAuth(map[string]config.Secret{"alice": config.Secret(hash)})
+
+	for _, tc := range []struct {
+		user, pass string
+		want       bool
+	}{
+		{user: "alice", pass: "secret", want: true},
+		{user: "alice", pass: "wrong"},
+		{user: "bob", pass: "secret"},
+		// Cached results must not change the outcome.
+		{user: "alice", pass: "secret", want: true},
+		{user: "alice", pass: "wrong"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth(tc.user, tc.pass)
+		if got := b.authenticate(r) != nil; got != tc.want {
+			t.Errorf("authenticate(%q, %q) = %t, want %t", tc.user, tc.pass, got, tc.want)
+		}
+	}
+	if len(b.cache) != 2 {
+		t.Errorf("expected 2 cached password checks, got %d", len(b.cache))
+	}
+}
+
+func TestBasicAuthCacheSize(t *testing.T) {
+	b := newBasicAuth(nil)
+	for i := 0; i < 2*maxBasicAuthCacheSize; i++ {
+		// An invalid hash fails fast without running bcrypt.
+		b.checkPassword("alice", "invalid", string(rune('a'+i)))
+	}
+	if len(b.cache) != maxBasicAuthCacheSize {
+		t.Errorf("expected %d cached password checks, got %d", maxBasicAuthCacheSize, len(b.cache))
+	}
+}
@@ -0,0 +1,535 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcPathPrefix = "/auth/"
+
+	sessionCookie = "promlens_session"
+	loginCookie   = "promlens_oidc_login"
+
+	// loginTimeout is how long a user has to complete the login at the
+	// identity provider.
+	loginTimeout = 10 * time.Minute
+	idpTimeout   = 10 * time.Second
+)
+
+var oidcLogins = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "promlens_auth_oidc_logins_total",
+	Help: "The total number of OIDC logins by result.",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(oidcLogins)
+}
+
+// OIDCConfig configures login via an OpenID Connect identity provider. The
+// identity is stored in a signed session cookie after the login.
+type OIDCConfig struct {
+	IssuerURL        string        `yaml:"issuer_url"`
+	ClientID         string        `yaml:"client_id"`
+	ClientSecret     config.Secret `yaml:"client_secret,omitempty"`
+	ClientSecretFile string        `yaml:"client_secret_file,omitempty"`
+	// RedirectURL defaults to "<external URL>/auth/callback".
+	RedirectURL string   `yaml:"redirect_url,omitempty"`
+	Scopes      []string `yaml:"scopes,omitempty"`
+	// UserClaim is the ID token or userinfo claim to use as the user name. If
+	// it is missing, the "email" and "sub" claims are used instead.
+	UserClaim   string `yaml:"user_claim,omitempty"`
+	GroupsClaim string `yaml:"groups_claim,omitempty"`
+	// SessionSecret is used to sign the session cookies. If neither it nor
+	// SessionSecretFile is set, a random secret is generated on startup, which
+	// logs out all users when PromLens restarts.
+	SessionSecret     config.Secret  `yaml:"session_secret,omitempty"`
+	SessionSecretFile string         `yaml:"session_secret_file,omitempty"`
+	SessionDuration   model.Duration `yaml:"session_duration,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *OIDCConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = OIDCConfig{
+		Scopes:          []string{"openid", "profile", "email"},
+		UserClaim:       "preferred_username",
+		GroupsClaim:     "groups",
+		SessionDuration: model.Duration(12 * time.Hour),
+	}
+	type plain OIDCConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.IssuerURL == "" {
+		return errors.New("missing issuer_url for OIDC authentication")
+	}
+	if c.ClientID == "" {
+		return errors.New("missing client_id for OIDC authentication")
+	}
+	if c.ClientSecret != "" && c.ClientSecretFile != "" {
+		return errors.New("at most one of client_secret and client_secret_file must be set")
+	}
+	if c.SessionSecret != "" && c.SessionSecretFile != "" {
+		return errors.New("at most one of session_secret and session_secret_file must be set")
+	}
+	if c.SessionDuration <= 0 {
+		return errors.New("session_duration must be positive")
+	}
+	return nil
+}
+
+// SetDirectory joins any relative file paths with dir.
+func (c *OIDCConfig) SetDirectory(dir string) {
+	c.ClientSecretFile = config.JoinDir(dir, c.ClientSecretFile)
+	c.SessionSecretFile = config.JoinDir(dir, c.SessionSecretFile)
+}
+
+// randomSessionKey is generated once per process, so that sessions survive
+// configuration reloads.
+var randomSessionKey = sync.OnceValue(func() []byte {
+	return []byte(rand.Text())
+})
+
+func readSecret(secret config.Secret, file string) (string, error) {
+	if file == "" {
+		return string(secret), nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// providerMetadata are the issuer and endpoints from the OpenID provider's
+// discovery document.
+type providerMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+
+	// verifier checks the signature and claims of ID tokens against the
+	// provider's keys.
+	verifier *oidc.IDTokenVerifier
+}
+
+type oidcAuth struct {
+	logger       *slog.Logger
+	cfg          *OIDCConfig
+	clientSecret string
+	redirectURL  string
+	sessionKey   []byte
+	// externalPath is the path under which PromLens is reachable by the browser.
+	externalPath string
+	secure       bool
+	client       *http.Client
+
+	// The provider metadata is discovered on first use, so that PromLens can
+	// start while the identity provider is unavailable.
+	mtx      sync.Mutex
+	provider *providerMetadata
+}
+
+func newOIDCAuth(logger *slog.Logger, cfg *OIDCConfig, externalURL *url.URL) (*oidcAuth, error) {
+	clientSecret, err := readSecret(cfg.ClientSecret, cfg.ClientSecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client secret: %w", err)
+	}
+	sessionSecret, err := readSecret(cfg.SessionSecret, cfg.SessionSecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading session secret: %w", err)
+	}
+	sessionKey := []byte(sessionSecret)
+	if sessionSecret == "" {
+		logger.Warn("No OIDC session secret configured, using a random secret. Users will have to log in again after a restart.")
+		sessionKey = randomSessionKey()
+	}
+
+	externalPath := strings.TrimRight(externalURL.Path, "/")
+	redirectURL := cfg.RedirectURL
+	if redirectURL == "" {
+		u := *externalURL
+		u.Path = externalPath + oidcPathPrefix + "callback"
+		redirectURL = u.String()
+	}
+
+	return &oidcAuth{
+		logger:       logger,
+		cfg:          cfg,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		sessionKey:   sessionKey,
+		externalPath: externalPath,
+		secure:       externalURL.Scheme == "https",
+		client:       &http.Client{Timeout: idpTimeout},
+	}, nil
+}
+
+func (o *oidcAuth) discover(ctx context.Context) (*providerMetadata, error) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	if o.provider != nil {
+		return o.provider, nil
+	}
+
+	u := strings.TrimRight(o.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching OpenID provider configuration: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching OpenID provider configuration: unexpected status code %d", resp.StatusCode)
+	}
+	var pm providerMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&pm); err != nil {
+		return nil, fmt.Errorf("error decoding OpenID provider configuration: %w", err)
+	}
+	if pm.AuthorizationEndpoint == "" || pm.TokenEndpoint == "" || pm.UserinfoEndpoint == "" || pm.JWKSURI == "" {
+		return nil, errors.New("OpenID provider configuration is missing the authorization, token, userinfo, or JWKS endpoint")
+	}
+	if strings.TrimRight(pm.Issuer, "/") != strings.TrimRight(o.cfg.IssuerURL, "/") {
+		return nil, fmt.Errorf("OpenID provider configuration has issuer %q, expected %q", pm.Issuer, o.cfg.IssuerURL)
+	}
+	// The key set is fetched in the background of later verifications, so it
+	// must not use the context of the current request.
+	keys := oidc.NewRemoteKeySet(oidc.ClientContext(context.Background(), o.client), pm.JWKSURI)
+	pm.verifier = oidc.NewVerifier(pm.Issuer, keys, &oidc.Config{
+		ClientID:             o.cfg.ClientID,
+		SupportedSigningAlgs: pm.SigningAlgs,
+	})
+	o.provider = &pm
+	return o.provider, nil
+}
+
+func (o *oidcAuth) oauth2Config(pm *providerMetadata) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     o.cfg.ClientID,
+		ClientSecret: o.clientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  pm.AuthorizationEndpoint,
+			TokenURL: pm.TokenEndpoint,
+		},
+		RedirectURL: o.redirectURL,
+		Scopes:      o.cfg.Scopes,
+	}
+}
+
+// mac computes the signature of a cookie payload. The cookie name is part of
+// the signed data, so that a value signed for one cookie (like the login state)
+// is not accepted as another one (like the session).
+func (o *oidcAuth) mac(cookie string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, o.sessionKey)
+	mac.Write([]byte(cookie))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// sign serializes v into a value for the named cookie that is authenticated
+// with the session key.
+func (o *oidcAuth) sign(cookie string, v interface{}) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(o.mac(cookie, payload)), nil
+}
+
+// verify checks the signature of a value created by sign for the named cookie
+// and deserializes it into v.
+func (o *oidcAuth) verify(cookie, s string, v interface{}) error {
+	encPayload, encSig, ok := strings.Cut(s, ".")
+	if !ok {
+		return errors.New("malformed cookie")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(sig, o.mac(cookie, payload)) {
+		return errors.New("invalid cookie signature")
+	}
+	return json.Unmarshal(payload, v)
+}
+
+func (o *oidcAuth) setCookie(w http.ResponseWriter, name, value string, expires time.Time) {
+	path := o.externalPath
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   o.secure,
+		// Lax is needed for the cookies to be sent along with the redirect
+		// back from the identity provider.
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+type session struct {
+	Identity Identity `json:"id"`
+	Expires  int64    `json:"exp"`
+}
+
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Redirect string `json:"redirect"`
+	Expires  int64  `json:"exp"`
+}
+
+func (o *oidcAuth) authenticate(r *http.Request) *Identity {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	var s session
+	if err := o.verify(sessionCookie, c.Value, &s); err != nil {
+		o.logger.Debug("Ignoring invalid session cookie", "err", err)
+		return nil
+	}
+	if time.Now().Unix() > s.Expires || s.Identity.User == "" {
+		return nil
+	}
+	return &s.Identity
+}
+
+// validRedirect returns whether the path is safe to redirect to after the
+// login, i.e. whether it stays on this host.
+func validRedirect(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "/\\")
+}
+
+// redirectToLogin sends the browser to the login endpoint, which returns it
+// to the given path (relative to the external URL) after the login.
+func (o *oidcAuth) redirectToLogin(w http.ResponseWriter, r *http.Request, path string) {
+	http.Redirect(w, r, o.externalPath+oidcPathPrefix+"login?redirect="+url.QueryEscape(path), http.StatusFound)
+}
+
+func (o *oidcAuth) handle(w http.ResponseWriter, r *http.Request, endpoint string) {
+	switch endpoint {
+	case "login":
+		o.handleLogin(w, r)
+	case "callback":
+		o.handleCallback(w, r)
+	case "logout":
+		// Only allow POST requests, so that other sites can't log users out
+		// by embedding the URL.
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Invalid HTTP method, use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		// Don't redirect back to PromLens, since the identity provider would
+		// likely log the user in again right away.
+		o.setCookie(w, sessionCookie, "", time.Unix(0, 0))
+		fmt.Fprintln(w, "You have been logged out of PromLens.")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (o *oidcAuth) handleLogin(w http.ResponseWriter, r *http.Request) {
+	pm, err := o.discover(r.Context())
+	if err != nil {
+		o.logger.Error("Error discovering OpenID provider", "issuer", o.cfg.IssuerURL, "err", err)
+		http.Error(w, "Error contacting the identity provider", http.StatusBadGateway)
+		return
+	}
+
+	redirect := r.FormValue("redirect")
+	if !validRedirect(redirect) {
+		redirect = "/"
+	}
+	ls := loginState{
+		State:    rand.Text(),
+		Nonce:    rand.Text(),
+		Verifier: oauth2.GenerateVerifier(),
+		Redirect: redirect,
+		Expires:  time.Now().Add(loginTimeout).Unix(),
+	}
+	v, err := o.sign(loginCookie, ls)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating login state: %v", err), http.StatusInternalServerError)
+		return
+	}
+	o.setCookie(w, loginCookie, v, time.Unix(ls.Expires, 0))
+	http.Redirect(w, r, o.oauth2Config(pm).AuthCodeURL(ls.State, oauth2.S256ChallengeOption(ls.Verifier), oidc.Nonce(ls.Nonce)), http.StatusFound)
+}
+
+func (o *oidcAuth) handleCallback(w http.ResponseWriter, r *http.Request) {
+	id, redirect, err := o.completeLogin(r)
+	if err != nil {
+		oidcLogins.WithLabelValues("failure").Inc()
+		o.logger.Warn("OIDC login failed", "err", err)
+		http.Error(w, fmt.Sprintf("Login failed: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	s := session{
+		Identity: *id,
+		Expires:  time.Now().Add(time.Duration(o.cfg.SessionDuration)).Unix(),
+	}
+	v, err := o.sign(sessionCookie, s)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	oidcLogins.WithLabelValues("success").Inc()
+	o.setCookie(w, loginCookie, "", time.Unix(0, 0))
+	o.setCookie(w, sessionCookie, v, time.Unix(s.Expires, 0))
+	http.Redirect(w, r, o.externalPath+redirect, http.StatusFound)
+}
+
+// completeLogin exchanges the authorization code from the callback request for
+// tokens, verifies the ID token, and returns the user's identity from the ID
+// token and userinfo claims, together with the path to return to.
+func (o *oidcAuth) completeLogin(r *http.Request) (*Identity, string, error) {
+	if e := r.FormValue("error"); e != "" {
+		return nil, "", fmt.Errorf("identity provider returned error %q: %s", e, r.FormValue("error_description"))
+	}
+	c, err := r.Cookie(loginCookie)
+	if err != nil {
+		return nil, "", errors.New("missing login state, please try again")
+	}
+	var ls loginState
+	if err := o.verify(loginCookie, c.Value, &ls); err != nil {
+		return nil, "", fmt.Errorf("invalid login state: %w", err)
+	}
+	if time.Now().Unix() > ls.Expires {
+		return nil, "", errors.New("login timed out, please try again")
+	}
+	if !hmac.Equal([]byte(r.FormValue("state")), []byte(ls.State)) {
+		return nil, "", errors.New("state mismatch")
+	}
+
+	ctx := context.WithValue(r.Context(), oauth2.HTTPClient, o.client)
+	pm, err := o.discover(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	oc := o.oauth2Config(pm)
+	tok, err := oc.Exchange(ctx, r.FormValue("code"), oauth2.VerifierOption(ls.Verifier))
+	if err != nil {
+		return nil, "", fmt.Errorf("error exchanging authorization code: %w", err)
+	}
+	rawIDToken, ok := tok.Extra("id_token").(string)
+	if !ok {
+		return nil, "", errors.New("token response contains no ID token")
+	}
+	idToken, err := pm.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid ID token: %w", err)
+	}
+	if !hmac.Equal([]byte(idToken.Nonce), []byte(ls.Nonce)) {
+		return nil, "", errors.New("ID token nonce mismatch")
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, "", fmt.Errorf("error decoding ID token claims: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pm.UserinfoEndpoint, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := oc.Client(ctx, tok).Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error fetching user info: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error fetching user info: unexpected status code %d", resp.StatusCode)
+	}
+	var userinfo map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&userinfo); err != nil {
+		return nil, "", fmt.Errorf("error decoding user info: %w", err)
+	}
+	// The user info must belong to the user that the ID token was issued to.
+	if sub, _ := userinfo["sub"].(string); sub != idToken.Subject {
+		return nil, "", fmt.Errorf("user info subject %q doesn't match ID token subject %q", sub, idToken.Subject)
+	}
+	for k, v := range userinfo {
+		claims[k] = v
+	}
+
+	id := o.identity(claims)
+	if id.User == "" {
+		return nil, "", errors.New("user info contains no user name")
+	}
+	return id, ls.Redirect, nil
+}
+
+func (o *oidcAuth) identity(claims map[string]interface{}) *Identity {
+	str := func(name string) string {
+		s, _ := claims[name].(string)
+		return s
+	}
+
+	id := &Identity{Email: str("email"), Method: MethodOIDC}
+	for _, name := range []string{o.cfg.UserClaim, "email", "sub"} {
+		if id.User = str(name); id.User != "" {
+			break
+		}
+	}
+	switch groups := claims[o.cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	case string:
+		id.Groups = []string{groups}
+	}
+	return id
+}
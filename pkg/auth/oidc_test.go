@@ -0,0 +1,358 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/prometheus/common/model"
+)
+
+// fakeIDP is an OpenID provider that issues ID tokens with the claims returned
+// by its claims function.
+type fakeIDP struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	claims   func(nonce string) map[string]interface{}
+	userinfo map[string]interface{}
+	// signKey signs the ID tokens. It defaults to key.
+	signKey *rsa.PrivateKey
+	// nonce is the nonce of the last authorization request.
+	nonce string
+}
+
+func newFakeIDP(t *testing.T) *fakeIDP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIDP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"userinfo_endpoint":      idp.URL + "/userinfo",
+			"jwks_uri":               idp.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		signKey := idp.signKey
+		if signKey == nil {
+			signKey = idp.key
+		}
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.RS256, Key: signKey},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"),
+		)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		payload, _ := json.Marshal(idp.claims(idp.nonce))
+		jws, err := signer.Sign(payload)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		idToken, _ := jws.CompactSerialize()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(idp.userinfo)
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func TestOIDCLogin(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		claims   func(iss, nonce string) map[string]interface{}
+		userinfo map[string]interface{}
+		signKey  *rsa.PrivateKey
+		wantUser string
+	}{
+		{
+			name:     "valid",
+			wantUser: "alice",
+		},
+		{
+			name:     "user from ID token",
+			userinfo: map[string]interface{}{"sub": "123"},
+			wantUser: "alice-id",
+		},
+		{
+			name: "wrong nonce",
+			claims: func(iss, nonce string) map[string]interface{} {
+				return idTokenClaims(iss, "other")
+			},
+		},
+		{
+			name: "wrong audience",
+			claims: func(iss, nonce string) map[string]interface{} {
+				c := idTokenClaims(iss, nonce)
+				c["aud"] = "other-client"
+				return c
+			},
+		},
+		{
+			name: "wrong issuer",
+			claims: func(iss, nonce string) map[string]interface{} {
+				return idTokenClaims("https://evil.example.com", nonce)
+			},
+		},
+		{
+			name: "expired",
+			claims: func(iss, nonce string) map[string]interface{} {
+				c := idTokenClaims(iss, nonce)
+				c["exp"] = time.Now().Add(-time.Minute).Unix()
+				return c
+			},
+		},
+		{
+			name:    "unknown key",
+			signKey: otherKey,
+		},
+		{
+			name:     "userinfo of other subject",
+			userinfo: map[string]interface{}{"sub": "456", "preferred_username": "mallory"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			idp := newFakeIDP(t)
+			idp.signKey = tc.signKey
+			idp.claims = func(nonce string) map[string]interface{} {
+				if tc.claims != nil {
+					return tc.claims(idp.URL, nonce)
+				}
+				return idTokenClaims(idp.URL, nonce)
+			}
+			idp.userinfo = tc.userinfo
+			if idp.userinfo == nil {
+				idp.userinfo = map[string]interface{}{"sub": "123", "preferred_username": "alice"}
+			}
+
+			externalURL, _ := url.Parse("http://promlens.example.com")
+			a, err := New(slog.New(slog.DiscardHandler), &Config{OIDC: &OIDCConfig{
+				IssuerURL:       idp.URL,
+				ClientID:        "promlens",
+				UserClaim:       "preferred_username",
+				SessionSecret:   "secret",
+				SessionDuration: model.Duration(time.Hour),
+			}}, externalURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := a.Handle("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, _ := FromContext(r.Context())
+				w.Write([]byte(id.User))
+			}))
+
+			// Start the login and follow the redirect to the identity provider.
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/login?redirect=/graph", nil))
+			if rec.Code != http.StatusFound {
+				t.Fatalf("login: got status %d", rec.Code)
+			}
+			authURL, err := url.Parse(rec.Header().Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			idp.nonce = authURL.Query().Get("nonce")
+			if idp.nonce == "" {
+				t.Fatal("authorization request contains no nonce")
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/callback?code=code&state="+url.QueryEscape(authURL.Query().Get("state")), nil)
+			for _, c := range rec.Result().Cookies() {
+				req.AddCookie(c)
+			}
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if tc.wantUser == "" {
+				if rec.Code != http.StatusUnauthorized {
+					t.Fatalf("callback: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+				}
+				return
+			}
+			if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/graph" {
+				t.Fatalf("callback: got status %d and location %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+			}
+
+			// The session cookie authenticates further requests.
+			req = httptest.NewRequest(http.MethodGet, "/api/parse", nil)
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == sessionCookie {
+					req.AddCookie(c)
+				}
+			}
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK || rec.Body.String() != tc.wantUser {
+				t.Errorf("got status %d and user %q, want user %q", rec.Code, rec.Body.String(), tc.wantUser)
+			}
+		})
+	}
+}
+
+func idTokenClaims(iss, nonce string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                iss,
+		"sub":                "123",
+		"aud":                "promlens",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              nonce,
+		"preferred_username": "alice-id",
+	}
+}
+
+func TestOIDCLogoutRequiresPost(t *testing.T) {
+	externalURL, _ := url.Parse("http://promlens.example.com")
+	a, err := New(slog.New(slog.DiscardHandler), &Config{OIDC: &OIDCConfig{
+		IssuerURL:       "http://idp.example.com",
+		ClientID:        "promlens",
+		SessionSecret:   "secret",
+		SessionDuration: model.Duration(time.Hour),
+	}}, externalURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := a.Handle("", http.NotFoundHandler())
+	for method, want := range map[string]int{
+		http.MethodGet:  http.StatusMethodNotAllowed,
+		http.MethodPost: http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/auth/logout", nil))
+		if rec.Code != want {
+			t.Errorf("%s: got status %d, want %d", method, rec.Code, want)
+		}
+		if cleared := len(rec.Result().Cookies()) > 0; cleared != (want == http.StatusOK) {
+			t.Errorf("%s: session cookie cleared: %t", method, cleared)
+		}
+	}
+}
+
+func TestOIDCSessionCookie(t *testing.T) {
+	idp := newFakeIDP(t)
+	externalURL, _ := url.Parse("http://promlens.example.com")
+	cfg := &OIDCConfig{
+		IssuerURL:       idp.URL,
+		ClientID:        "promlens",
+		SessionSecret:   "secret",
+		SessionDuration: model.Duration(time.Hour),
+	}
+	a, err := New(slog.New(slog.DiscardHandler), &Config{OIDC: cfg}, externalURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := a.Handle("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := FromContext(r.Context())
+		w.Write([]byte(id.User))
+	}))
+	o, err := newOIDCAuth(slog.New(slog.DiscardHandler), cfg, externalURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An anonymous client can obtain a signed login state cookie.
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	var loginValue string
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == loginCookie {
+			loginValue = c.Value
+		}
+	}
+	if loginValue == "" {
+		t.Fatal("login response sets no login state cookie")
+	}
+
+	sign := func(s session) string {
+		v, err := o.sign(sessionCookie, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	for _, tc := range []struct {
+		name     string
+		value    string
+		wantUser string
+	}{
+		{
+			name:     "valid session",
+			value:    sign(session{Identity: Identity{User: "alice", Method: MethodOIDC}, Expires: exp}),
+			wantUser: "alice",
+		},
+		{
+			name:  "login state replayed as session",
+			value: loginValue,
+		},
+		{
+			name:  "empty user",
+			value: sign(session{Identity: Identity{Method: MethodOIDC}, Expires: exp}),
+		},
+		{
+			name:  "expired session",
+			value: sign(session{Identity: Identity{User: "alice", Method: MethodOIDC}, Expires: time.Now().Add(-time.Minute).Unix()}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/parse", nil)
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: tc.value})
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if tc.wantUser != "" {
+				if rec.Code != http.StatusOK || rec.Body.String() != tc.wantUser {
+					t.Errorf("got status %d and user %q, want user %q", rec.Code, rec.Body.String(), tc.wantUser)
+				}
+				return
+			}
+			if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusFound {
+				t.Errorf("got status %d and body %q, want the request to be rejected", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
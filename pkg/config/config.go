@@ -0,0 +1,290 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/prometheus/promlens/pkg/audit"
+	"github.com/prometheus/promlens/pkg/auth"
+	"github.com/prometheus/promlens/pkg/grafana"
+	"github.com/prometheus/promlens/pkg/promproxy"
+)
+
+// Config is the configuration that can be provided in the PromLens
+// configuration file. Sections that are omitted in the file fall back to the
+// corresponding command-line flags.
+type Config struct {
+	PrometheusServers []promproxy.ServerConfig `yaml:"prometheus_servers,omitempty"`
+	Grafana           *GrafanaConfig           `yaml:"grafana,omitempty"`
+	SharedLinks       *SharedLinksConfig       `yaml:"shared_links,omitempty"`
+	UI                *UIConfig                `yaml:"ui,omitempty"`
+	Auth              *auth.Config             `yaml:"auth,omitempty"`
+	AuditLog          *audit.Config            `yaml:"audit_log,omitempty"`
+}
+
+// DefaultDatasourceCacheTTL is the default interval at which the Grafana
+// datasource list is refreshed.
+const DefaultDatasourceCacheTTL = model.Duration(time.Minute)
+
+// DefaultDatasourceHealthCheckInterval is the default interval at which the
+// Grafana datasources are probed.
+const DefaultDatasourceHealthCheckInterval = model.Duration(time.Minute)
+
+// GrafanaConfig configures the Grafana datasource integration.
+type GrafanaConfig struct {
+	URL                 string             `yaml:"url"`
+	APIToken            config_util.Secret `yaml:"api_token,omitempty"`
+	APITokenFile        string             `yaml:"api_token_file,omitempty"`
+	DefaultDatasourceID int64              `yaml:"default_datasource_id,omitempty"`
+	// DatasourceCacheTTL is the interval at which the datasource list is
+	// refreshed.
+	DatasourceCacheTTL model.Duration `yaml:"datasource_cache_ttl,omitempty"`
+	// DatasourceHealthCheckInterval is the interval at which the datasources
+	// are probed. If 0, health checks are disabled.
+	DatasourceHealthCheckInterval model.Duration `yaml:"datasource_health_check_interval"`
+	// DatasourceTypes are the types of datasources that speak the Prometheus
+	// query API. If unset, only "prometheus" datasources are used.
+	DatasourceTypes []grafana.DatasourceTypeConfig `yaml:"datasource_types,omitempty"`
+	// UserAuth configures as which user datasource requests are sent. If
+	// unset, they are sent with the API token.
+	UserAuth *grafana.UserAuthConfig `yaml:"user_auth,omitempty"`
+	// Orgs are the organizations to offer the datasources of. If unset, only
+	// the datasources of the API token's organization are offered.
+	Orgs []GrafanaOrgConfig `yaml:"orgs,omitempty"`
+	// DashboardFolderUID is the UID of the folder that new dashboards are
+	// exported to. If unset, they are created in the General folder.
+	DashboardFolderUID string `yaml:"dashboard_folder_uid,omitempty"`
+	// EnableDashboardImport allows importing dashboards with the API token,
+	// which lets every PromLens user read every dashboard that the token can
+	// access. With a per-user UserAuth mode, importing is always enabled.
+	EnableDashboardImport bool `yaml:"enable_dashboard_import,omitempty"`
+	// EnableDashboardExport enables exporting queries to Grafana dashboards.
+	// It requires a per-user UserAuth mode, so that dashboards are never
+	// written with the API token.
+	EnableDashboardExport bool `yaml:"enable_dashboard_export,omitempty"`
+}
+
+// GrafanaOrgConfig configures access to a Grafana organization.
+type GrafanaOrgConfig struct {
+	ID int64 `yaml:"id"`
+	// APIToken and APITokenFile set a token of the organization. If neither
+	// is set, the main API token is used with the X-Grafana-Org-Id header,
+	// which requires it to be a server admin token.
+	APIToken     config_util.Secret `yaml:"api_token,omitempty"`
+	APITokenFile string             `yaml:"api_token_file,omitempty"`
+	// DashboardFolderUID overrides the folder that new dashboards in the
+	// organization are exported to.
+	DashboardFolderUID string `yaml:"dashboard_folder_uid,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *GrafanaOrgConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain GrafanaOrgConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.ID <= 0 {
+		return errors.New("missing or invalid Grafana organization ID")
+	}
+	if c.APIToken != "" && c.APITokenFile != "" {
+		return fmt.Errorf("at most one of api_token and api_token_file must be set for Grafana organization %d", c.ID)
+	}
+	return nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *GrafanaConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.DatasourceCacheTTL = DefaultDatasourceCacheTTL
+	c.DatasourceHealthCheckInterval = DefaultDatasourceHealthCheckInterval
+	type plain GrafanaConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return errors.New("missing Grafana URL")
+	}
+	if c.APIToken == "" && c.APITokenFile == "" {
+		return errors.New("one of api_token or api_token_file must be set when configuring Grafana")
+	}
+	if c.APIToken != "" && c.APITokenFile != "" {
+		return errors.New("at most one of api_token and api_token_file must be set")
+	}
+	if c.DatasourceCacheTTL <= 0 {
+		return errors.New("datasource_cache_ttl must be positive")
+	}
+	if c.DatasourceHealthCheckInterval < 0 {
+		return errors.New("datasource_health_check_interval must not be negative")
+	}
+	if c.EnableDashboardExport && (c.UserAuth == nil || c.UserAuth.Mode == grafana.UserAuthToken) {
+		return fmt.Errorf("enable_dashboard_export requires the %q or %q user_auth mode", grafana.UserAuthSession, grafana.UserAuthProxy)
+	}
+	orgs := make(map[int64]struct{}, len(c.Orgs))
+	for _, o := range c.Orgs {
+		if _, ok := orgs[o.ID]; ok {
+			return fmt.Errorf("duplicate Grafana organization %d", o.ID)
+		}
+		orgs[o.ID] = struct{}{}
+	}
+	return nil
+}
+
+// SharedLinksConfig configures the link sharing backend.
+type SharedLinksConfig struct {
+	GCS *GCSConfig `yaml:"gcs,omitempty"`
+	SQL *SQLConfig `yaml:"sql,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SharedLinksConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SharedLinksConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.GCS != nil && c.SQL != nil {
+		return errors.New("multiple link sharing backends are configured - please specify only one")
+	}
+	return nil
+}
+
+// GCSConfig configures storing shared links in a GCS bucket.
+type GCSConfig struct {
+	Bucket string `yaml:"bucket"`
+}
+
+// SQLConfig configures storing shared links in a SQL database.
+type SQLConfig struct {
+	Driver       string             `yaml:"driver"`
+	DSN          config_util.Secret `yaml:"dsn"`
+	CreateTables bool               `yaml:"create_tables"`
+	Retention    model.Duration     `yaml:"retention,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SQLConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.CreateTables = true
+	type plain SQLConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.DSN == "" {
+		return errors.New("missing SQL DSN for shared links")
+	}
+	return nil
+}
+
+// UIConfig configures the defaults of the web UI.
+type UIConfig struct {
+	DefaultPrometheusURL string `yaml:"default_prometheus_url,omitempty"`
+	// DefaultPrometheusServer is the name of the Prometheus server from the
+	// "prometheus_servers" section to select by default.
+	DefaultPrometheusServer string `yaml:"default_prometheus_server,omitempty"`
+}
+
+// SetDirectory joins any relative file paths with dir.
+func (c *Config) SetDirectory(dir string) {
+	for i := range c.PrometheusServers {
+		c.PrometheusServers[i].HTTPClientConfig.SetDirectory(dir)
+	}
+	if c.Grafana != nil {
+		c.Grafana.APITokenFile = config_util.JoinDir(dir, c.Grafana.APITokenFile)
+		for i := range c.Grafana.Orgs {
+			c.Grafana.Orgs[i].APITokenFile = config_util.JoinDir(dir, c.Grafana.Orgs[i].APITokenFile)
+		}
+	}
+	if c.Auth != nil {
+		c.Auth.SetDirectory(dir)
+	}
+	if c.AuditLog != nil {
+		c.AuditLog.SetDirectory(dir)
+	}
+}
+
+// Load parses the YAML input s into a Config.
+func Load(s string) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict([]byte(s), cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.UI != nil && cfg.UI.DefaultPrometheusServer != "" {
+		if cfg.UI.DefaultPrometheusURL != "" {
+			return nil, errors.New("at most one of default_prometheus_url and default_prometheus_server must be set")
+		}
+		found := false
+		for _, sc := range cfg.PrometheusServers {
+			found = found || sc.Name == cfg.UI.DefaultPrometheusServer
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown default Prometheus server %q", cfg.UI.DefaultPrometheusServer)
+		}
+	}
+	if cfg.Grafana != nil && cfg.Grafana.UserAuth != nil && cfg.Grafana.UserAuth.Mode == grafana.UserAuthProxy {
+		// Grafana trusts the user name that PromLens sends, so it must come
+		// from an authentication method that clients can't spoof.
+		if cfg.Auth == nil {
+			return nil, fmt.Errorf("the %q Grafana user_auth mode requires PromLens authentication", grafana.UserAuthProxy)
+		}
+		if cfg.Auth.TrustedHeader != nil && len(cfg.Auth.TrustedHeader.TrustedProxies) == 0 {
+			return nil, fmt.Errorf("the %q Grafana user_auth mode requires trusted_proxies for trusted header authentication", grafana.UserAuthProxy)
+		}
+	}
+	return cfg, nil
+}
+
+// LoadFile parses the given YAML file into a Config.
+func LoadFile(filename string) (*Config, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Load(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parsing YAML file %s: %w", filename, err)
+	}
+	cfg.SetDirectory(filepath.Dir(filename))
+	return cfg, nil
+}
+
+// Merge returns a copy of the configuration in which all sections that are not
+// set are taken from defaults.
+func (c Config) Merge(defaults Config) Config {
+	if c.PrometheusServers == nil {
+		c.PrometheusServers = defaults.PrometheusServers
+	}
+	if c.Grafana == nil {
+		c.Grafana = defaults.Grafana
+	}
+	if c.SharedLinks == nil {
+		c.SharedLinks = defaults.SharedLinks
+	}
+	if c.UI == nil {
+		c.UI = defaults.UI
+	}
+	if c.Auth == nil {
+		c.Auth = defaults.Auth
+	}
+	if c.AuditLog == nil {
+		c.AuditLog = defaults.AuditLog
+	}
+	return c
+}
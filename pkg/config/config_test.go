@@ -0,0 +1,87 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestLoadGrafanaUserAuth(t *testing.T) {
+	const grafana = `
+grafana:
+  url: https://grafana.example.com
+  api_token: secret
+  user_auth:
+    mode: `
+	for _, tc := range []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{
+			name:   "session mode without auth",
+			config: grafana + "session\n",
+		},
+		{
+			name:    "auth proxy mode without auth",
+			config:  grafana + "auth_proxy\n",
+			wantErr: true,
+		},
+		{
+			name: "auth proxy mode with basic auth",
+			config: grafana + `auth_proxy
+auth:
+  basic_auth_users:
+    alice: $2y$10$abcdefghijklmnopqrstuv
+`,
+		},
+		{
+			name: "auth proxy mode with trusted header auth without proxies",
+			config: grafana + `auth_proxy
+auth:
+  trusted_header:
+    user_header: X-User
+`,
+			wantErr: true,
+		},
+		{
+			name: "auth proxy mode with trusted header auth",
+			config: grafana + `auth_proxy
+auth:
+  trusted_header:
+    user_header: X-User
+    trusted_proxies: [10.0.0.0/8]
+`,
+		},
+		{
+			name:    "dashboard export in token mode",
+			config:  grafana + "token\n  enable_dashboard_export: true\n",
+			wantErr: true,
+		},
+		{
+			name:   "dashboard export in session mode",
+			config: grafana + "session\n  enable_dashboard_export: true\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(tc.config)
+			if tc.wantErr && err == nil {
+				t.Fatal("expected error, got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package functiondocs provides the PromQL function documentation of the
+// Prometheus version whose parser is built into PromLens.
+package functiondocs
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/grafana/regexp"
+	prom_httputil "github.com/prometheus/prometheus/util/httputil"
+	"github.com/russross/blackfriday/v2"
+
+	"github.com/prometheus/promlens/pkg/parser"
+)
+
+// functions.md is a vendored copy of "docs/querying/functions.md" from the
+// Prometheus module version in go.mod, so that the docs always match the parser
+// version and building PromLens does not require network access. After updating
+// the Prometheus dependency, refresh it by running "go generate" in this
+// directory and commit the result. The copy only replaces functions.md if the
+// module could be downloaded, so a failed refresh leaves the vendored file
+// intact.
+//
+//go:generate sh -c "go mod download github.com/prometheus/prometheus && cp \"$(go list -m -f '{{.Dir}}' github.com/prometheus/prometheus)/docs/querying/functions.md\" functions.md"
+
+//go:embed functions.md
+var functionsMD []byte
+
+var (
+	sectionRe      = regexp.MustCompile("^## (.+)$")
+	headingFuncRe  = regexp.MustCompile("`([a-z0-9_]+)\\(\\)`")
+	listItemFuncRe = regexp.MustCompile("^\\* `([a-z0-9_]+)\\(")
+)
+
+// Parse splits a Prometheus "functions.md" documentation file into Markdown
+// documentation snippets by function name.
+//
+// Sections that document a single function or several named functions (like
+// "## `histogram_count()` and `histogram_sum()`") are assigned to those
+// functions. Sections that document a group of functions without naming them
+// in the heading (like "## `<aggregation>_over_time()`") are assigned to all
+// functions that are listed in the section's bullet points instead.
+func Parse(r io.Reader) (map[string]string, error) {
+	docs := map[string]string{}
+
+	var (
+		inSection    bool
+		headingFuncs []string
+		listFuncs    []string
+		sectionDocs  strings.Builder
+	)
+
+	saveSection := func() {
+		if !inSection {
+			return
+		}
+		fns := headingFuncs
+		if len(fns) == 0 {
+			fns = listFuncs
+		}
+		for _, fn := range fns {
+			docs[fn] = sectionDocs.String()
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+
+		if m := sectionRe.FindStringSubmatch(line); m != nil {
+			saveSection()
+
+			inSection = true
+			headingFuncs = nil
+			listFuncs = nil
+			sectionDocs.Reset()
+			for _, fm := range headingFuncRe.FindAllStringSubmatch(m[1], -1) {
+				headingFuncs = append(headingFuncs, fm[1])
+			}
+			continue
+		}
+
+		if m := listItemFuncRe.FindStringSubmatch(line); m != nil {
+			listFuncs = append(listFuncs, m[1])
+		}
+		sectionDocs.WriteString(line)
+		sectionDocs.WriteString("\n")
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("error reading function docs: %w", err)
+	}
+	saveSection()
+
+	return docs, nil
+}
+
+var parsedDocs = sync.OnceValues(func() (map[string]string, error) {
+	return Parse(bytes.NewReader(functionsMD))
+})
+
+// Markdown returns the built-in Markdown documentation by function name. The
+// returned map is shared and must not be modified.
+func Markdown() (map[string]string, error) {
+	return parsedDocs()
+}
+
+// Render renders Markdown function documentation to HTML.
+func Render(md string) string {
+	return string(blackfriday.Run([]byte(md)))
+}
+
+var renderedDocs = sync.OnceValues(func() ([]byte, error) {
+	docs, err := Markdown()
+	if err != nil {
+		return nil, err
+	}
+
+	html := make(map[string]string, len(docs))
+	for fn, md := range docs {
+		html[fn] = Render(md)
+	}
+	return json.Marshal(html)
+})
+
+// Handle responds with a JSON object mapping function names to their
+// documentation, rendered as HTML.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	prom_httputil.SetCORS(w, parser.CORSOrigin, r)
+	buf, err := renderedDocs()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error rendering function docs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf)
+}
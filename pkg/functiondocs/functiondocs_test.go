@@ -0,0 +1,91 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functiondocs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	md := "# Intro\n" +
+		"\n" +
+		"## `abs()`\n" +
+		"\n" +
+		"Absolute value.\n" +
+		"## `histogram_count()` and `histogram_sum()`\n" +
+		"Count and sum.\n" +
+		"## `<aggregation>_over_time()`\n" +
+		"* `avg_over_time(range-vector)`: average.\n" +
+		"* `max_over_time(range-vector)`: maximum.\n"
+
+	docs, err := Parse(strings.NewReader(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	over := "* `avg_over_time(range-vector)`: average.\n* `max_over_time(range-vector)`: maximum.\n"
+	exp := map[string]string{
+		"abs":             "\nAbsolute value.\n",
+		"histogram_count": "Count and sum.\n",
+		"histogram_sum":   "Count and sum.\n",
+		"avg_over_time":   over,
+		"max_over_time":   over,
+	}
+	if len(docs) != len(exp) {
+		t.Fatalf("expected docs for %d functions, got %d: %v", len(exp), len(docs), docs)
+	}
+	for fn, want := range exp {
+		if got := docs[fn]; got != want {
+			t.Errorf("%s: expected docs %q, got %q", fn, want, got)
+		}
+	}
+}
+
+// TestBuiltinDocs guards against an empty or truncated vendored functions.md.
+func TestBuiltinDocs(t *testing.T) {
+	docs, err := Markdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range []string{"abs", "rate", "histogram_quantile", "histogram_sum", "avg_over_time", "acos", "pi"} {
+		if !strings.Contains(docs[fn], fn) {
+			t.Errorf("missing docs for function %q", fn)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/function_docs", nil)
+	req.Header.Set("Origin", "https://tools.example.com")
+	Handle(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected CORS header allowing all origins, got %q", got)
+	}
+
+	var docs map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &docs); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(docs["rate"], "<code>rate(v range-vector)</code>") {
+		t.Errorf("unexpected rendered docs for rate(): %q", docs["rate"])
+	}
+}
@@ -15,26 +15,77 @@ package grafana
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/prometheus/promlens/pkg/audit"
 )
 
 type Backend struct {
 	proxy     *httputil.ReverseProxy
 	url       string
 	authToken string
+	userAuth  UserAuthConfig
+	// types are the accepted datasource types by name.
+	types map[string]DatasourceTypeConfig
+	orgs  []OrgOptions
+	// folderUID is the folder that new dashboards are exported to.
+	folderUID string
+	// dashboardImport allows importing dashboards with the API token.
+	dashboardImport bool
+	// dashboardExport enables exporting queries to dashboards.
+	dashboardExport bool
+	cache           *datasourceCache
+	// health is nil if health checks are disabled.
+	health *healthChecker
+}
+
+// Options configures a Backend.
+type Options struct {
+	Logger *slog.Logger
+	// URL is the URL of the Grafana installation.
+	URL       string
+	AuthToken string
+	// UserAuth configures as which user datasource requests are sent. If nil,
+	// all requests are sent with the API token.
+	UserAuth *UserAuthConfig
+	// DatasourceCacheTTL is the interval at which the cached datasource list
+	// is refreshed.
+	DatasourceCacheTTL time.Duration
+	// DatasourceTypes are the accepted datasource types. If empty,
+	// DefaultDatasourceTypes are used.
+	DatasourceTypes []DatasourceTypeConfig
+	// Orgs are the organizations to offer the datasources of. If empty, only
+	// the datasources of the token's organization are offered.
+	Orgs []OrgOptions
+	// DashboardFolderUID is the UID of the folder that new dashboards are
+	// exported to. If empty, they are created in the General folder.
+	DashboardFolderUID string
+	// HealthCheckInterval is the interval at which the datasources are probed.
+	// If 0, health checks are disabled.
+	HealthCheckInterval time.Duration
+	// DashboardImport allows importing dashboards with the API token. With a
+	// per-user UserAuth mode, importing is always allowed.
+	DashboardImport bool
+	// DashboardExport enables exporting queries to dashboards. It requires a
+	// per-user UserAuth mode.
+	DashboardExport bool
 }
 
 type DatasourceSettings struct {
 	ID                int64                  `json:"id"`
+	UID               string                 `json:"uid"`
 	OrgID             int64                  `json:"orgID"`
 	Name              string                 `json:"name"`
 	Type              string                 `json:"type"`
@@ -77,33 +128,97 @@ func singleJoiningSlash(a, b string) string {
 	return a + b
 }
 
-func NewBackend(grafanaURL string, authToken string) (*Backend, error) {
-	target, err := url.Parse(grafanaURL)
+// NewBackend creates a Backend and starts refreshing its datasource list and
+// checking the datasources' health in the background. Call Close to stop it.
+func NewBackend(o Options) (*Backend, error) {
+	target, err := url.Parse(o.URL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid Grafana URL: %w", err)
 	}
+	if o.DatasourceCacheTTL <= 0 {
+		return nil, errors.New("datasource cache TTL must be positive")
+	}
+	if o.HealthCheckInterval < 0 {
+		return nil, errors.New("health check interval must not be negative")
+	}
+	types, err := datasourceTypesMap(o.DatasourceTypes)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Backend{
-		proxy: &httputil.ReverseProxy{
-			Director: func(req *http.Request) {
-				req.URL.Scheme = target.Scheme
-				req.URL.Host = target.Host
-				req.Host = target.Host
-				req.URL.Path = singleJoiningSlash(target.Path, strings.TrimPrefix(req.URL.Path, "/api/grafana/"))
-				log.Printf("Proxying to Grafana at %s...", req.URL.Path)
-				req.Header.Set("Authorization", "Bearer "+authToken)
-				if _, ok := req.Header["User-Agent"]; !ok {
-					// explicitly disable User-Agent so it's not set to default value
-					req.Header.Set("User-Agent", "")
-				}
-			},
+	b := &Backend{
+		url:       o.URL,
+		authToken: o.AuthToken,
+		userAuth:  DefaultUserAuthConfig,
+		types:     types,
+		orgs:      o.Orgs,
+		folderUID: o.DashboardFolderUID,
+
+		dashboardImport: o.DashboardImport,
+		dashboardExport: o.DashboardExport,
+	}
+	if o.UserAuth != nil {
+		b.userAuth = *o.UserAuth
+	}
+	if b.dashboardExport && b.userAuth.Mode == UserAuthToken {
+		return nil, fmt.Errorf("dashboard export requires the %q or %q user authentication mode", UserAuthSession, UserAuthProxy)
+	}
+	b.proxy = &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.Host = target.Host
+			req.URL.Path = singleJoiningSlash(target.Path, strings.TrimPrefix(req.URL.Path, "/api/grafana/"))
+			log.Printf("Proxying to Grafana at %s...", req.URL.Path)
+			ds, _ := req.Context().Value(datasourceKey{}).(DatasourceSettings)
+			b.setCredentials(req, req, ds.OrgID)
+			if _, ok := req.Header["User-Agent"]; !ok {
+				// explicitly disable User-Agent so it's not set to default value
+				req.Header.Set("User-Agent", "")
+			}
 		},
-		url:       grafanaURL,
-		authToken: authToken,
-	}, nil
+	}
+	b.cache = newDatasourceCache(o.Logger, o.DatasourceCacheTTL, b.GetDatasources)
+	if o.HealthCheckInterval > 0 {
+		b.health = newHealthChecker(b, o.Logger, o.HealthCheckInterval)
+	}
+	return b, nil
 }
 
-func (b *Backend) GetDatasources() (dsSettings []DatasourceSettings, err error) {
+// Datasources returns the cached list of Prometheus datasources along with
+// its freshness. If Grafana can't be reached, the last successfully fetched
+// list is returned.
+func (b *Backend) Datasources() ([]DatasourceSettings, DatasourceStatus) {
+	return b.cache.get()
+}
+
+// Orgs returns the cached list of organizations, or nil if no organizations
+// are configured.
+func (b *Backend) Orgs() []Org {
+	return b.cache.getOrgs()
+}
+
+// Health returns the result of the last health check of the datasource with
+// the given ID, or false if it hasn't been checked yet.
+func (b *Backend) Health(datasourceID int64) (DatasourceHealth, bool) {
+	if b.health == nil {
+		return DatasourceHealth{}, false
+	}
+	return b.health.get(datasourceID)
+}
+
+// Close stops refreshing the datasource list and checking its health.
+func (b *Backend) Close() {
+	if b.health != nil {
+		b.health.close()
+	}
+	b.cache.close()
+}
+
+// GetDatasources fetches the datasources of the accepted types from Grafana.
+// If organizations are configured, the datasources of all of them are fetched,
+// along with the organizations' names.
+func (b *Backend) GetDatasources() (dsSettings []DatasourceSettings, orgs []Org, err error) {
 	datasourceLookups.Inc()
 	defer func() {
 		if err != nil {
@@ -111,43 +226,76 @@ func (b *Backend) GetDatasources() (dsSettings []DatasourceSettings, err error)
 		}
 	}()
 
-	// TODO: Make timeout configurable.
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	req, err := http.NewRequestWithContext(ctx, "GET", singleJoiningSlash(b.url, "/api/datasources"), nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+b.authToken)
-
-	c := &http.Client{}
-	resp, err := c.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching datasources: %w", err)
+	ctx := context.Background()
+	if len(b.orgs) == 0 {
+		ds, err := b.orgDatasources(ctx, 0)
+		return ds, nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("bad response status: %v", resp.Status)
+	for _, o := range b.orgs {
+		var org Org
+		if err := b.apiRequest(ctx, nil, o.ID, http.MethodGet, "/api/org", nil, &org); err != nil {
+			return nil, nil, fmt.Errorf("error fetching organization %d: %w", o.ID, err)
+		}
+		ds, err := b.orgDatasources(ctx, o.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error fetching datasources of organization %d: %w", o.ID, err)
+		}
+		orgs = append(orgs, Org{ID: o.ID, Name: org.Name})
+		dsSettings = append(dsSettings, ds...)
 	}
+	return dsSettings, orgs, nil
+}
 
+// orgDatasources fetches the datasources of the accepted types in the
+// organization with the given ID.
+func (b *Backend) orgDatasources(ctx context.Context, orgID int64) ([]DatasourceSettings, error) {
 	var ds []DatasourceSettings
-	if err = json.NewDecoder(resp.Body).Decode(&ds); err != nil {
-		return nil, fmt.Errorf("error unmarshaling datasources: %w", err)
+	if err := b.apiRequest(ctx, nil, orgID, http.MethodGet, "/api/datasources", nil, &ds); err != nil {
+		return nil, err
 	}
 
 	promDS := make([]DatasourceSettings, 0, len(ds))
 	for _, s := range ds {
-		if s.Type == "prometheus" {
+		if _, ok := b.types[s.Type]; ok {
+			if orgID != 0 {
+				s.OrgID = orgID
+			}
 			promDS = append(promDS, s)
 		}
 	}
-
 	return promDS, nil
 }
 
-func (b *Backend) Handle(routePrefix string) http.HandlerFunc {
+// PrometheusAPI returns a client for the Prometheus datasource with the given
+// ID that sends its queries through the Grafana datasource proxy, on behalf of
+// the user of the incoming request r.
+func (b *Backend) PrometheusAPI(r *http.Request, datasourceID int64) (v1.API, error) {
+	if err := b.checkCredentials(r); err != nil {
+		return nil, err
+	}
+	ds, err := b.datasource(datasourceRef{id: datasourceID})
+	if err != nil {
+		return nil, err
+	}
+	c, err := api.NewClient(api.Config{
+		Address:      singleJoiningSlash(b.url, fmt.Sprintf("/api/datasources/proxy/%d", datasourceID)),
+		RoundTripper: &userRoundTripper{b: b, in: r, ds: ds, next: api.DefaultRoundTripper},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating Prometheus API client: %w", err)
+	}
+	return v1.NewAPI(c), nil
+}
+
+// datasourceKey is the context key of the datasource that a proxied request
+// is sent to.
+type datasourceKey struct{}
+
+// datasourceProxyPrefix is the path below which datasource requests are proxied.
+const datasourceProxyPrefix = "/api/grafana/api/datasources/proxy/"
+
+func (b *Backend) Handle(routePrefix string, al *audit.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		r.URL.Path = strings.TrimPrefix(r.URL.Path, routePrefix)
 		// Only allow datasource proxy requests and nothing else (since the Grafana token is an admin token and thus powerful).
@@ -162,10 +310,40 @@ func (b *Backend) Handle(routePrefix string) http.HandlerFunc {
 		//
 		// Tested this and it works.
 		// TODO: If we only want to proxy datasource requests, why not just register the entire Grafana backend on that path?
-		if strings.HasPrefix(r.URL.Path, "/api/grafana/api/datasources/proxy/") {
-			b.proxy.ServeHTTP(w, r)
-		} else {
+		proxyPath, ok := strings.CutPrefix(r.URL.Path, datasourceProxyPrefix)
+		if !ok {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		ref, apiPath, err := parseProxyPath(proxyPath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Only proxy to datasources of the accepted types.
+		ds, err := b.datasource(ref)
+		if errors.Is(err, errAmbiguousDatasource) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Unknown datasource", http.StatusNotFound)
+			return
+		}
+		if err := b.checkCredentials(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if al.Enabled() {
+			al.Log(r, audit.Event{
+				Action:        audit.ActionProxy,
+				DatasourceID:  ref.id,
+				DatasourceUID: ref.uid,
+				Path:          apiPath,
+				Query:         audit.Query(r),
+			})
 		}
+		b.setDatasourceHeaders(r.Header, ds)
+		b.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), datasourceKey{}, ds)))
 	}
 }
@@ -0,0 +1,162 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var datasourceCacheAge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "promlens_grafana_datasource_cache_last_update_timestamp_seconds",
+	Help: "Timestamp of the last successful refresh of the cached Grafana datasource list.",
+})
+
+func init() {
+	prometheus.MustRegister(datasourceCacheAge)
+}
+
+// cacheRetryInterval is the interval at which the datasources are fetched as
+// long as no refresh has succeeded yet.
+const cacheRetryInterval = 5 * time.Second
+
+// DatasourceStatus describes the freshness of the cached datasource list.
+type DatasourceStatus struct {
+	// LastUpdate is the time of the last successful refresh, or zero if the
+	// datasources have never been fetched successfully.
+	LastUpdate time.Time `json:"lastUpdate"`
+	// Stale is true if the last refresh failed and older datasources are served.
+	Stale bool `json:"stale"`
+	// Error is the error of the last refresh, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// datasourceCache caches the datasource list and refreshes it in the
+// background, so that page loads neither wait for nor fail because of Grafana.
+type datasourceCache struct {
+	logger *slog.Logger
+	ttl    time.Duration
+	fetch  func() ([]DatasourceSettings, []Org, error)
+
+	stop chan struct{}
+	done chan struct{}
+
+	mtx         sync.RWMutex
+	datasources []DatasourceSettings
+	orgs        []Org
+	lastUpdate  time.Time
+	lastErr     error
+}
+
+func newDatasourceCache(logger *slog.Logger, ttl time.Duration, fetch func() ([]DatasourceSettings, []Org, error)) *datasourceCache {
+	c := &datasourceCache{
+		logger: logger,
+		ttl:    ttl,
+		fetch:  fetch,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *datasourceCache) run() {
+	defer close(c.done)
+
+	for {
+		wait := c.ttl
+		if !c.refresh() {
+			wait = min(wait, cacheRetryInterval)
+		}
+		select {
+		case <-time.After(wait):
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// refresh fetches the datasources and returns whether any refresh has
+// succeeded so far.
+func (c *datasourceCache) refresh() bool {
+	ds, orgs, err := c.fetch()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.lastErr = err
+	if err != nil {
+		c.logger.Warn("Error refreshing Grafana datasources", "err", err)
+		return !c.lastUpdate.IsZero()
+	}
+	c.datasources = ds
+	c.orgs = orgs
+	c.lastUpdate = time.Now()
+	datasourceCacheAge.Set(float64(c.lastUpdate.Unix()))
+	return true
+}
+
+// get returns a copy of the cached datasources without waiting for Grafana.
+// Until the first refresh has succeeded, the list is empty and the status
+// reports an error, while the background loop keeps retrying.
+func (c *datasourceCache) get() ([]DatasourceSettings, DatasourceStatus) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	status := DatasourceStatus{LastUpdate: c.lastUpdate}
+	switch {
+	case c.lastErr != nil:
+		status.Error = c.lastErr.Error()
+	case c.lastUpdate.IsZero():
+		status.Error = "datasources have not been fetched yet"
+	}
+	status.Stale = status.Error != "" && !c.lastUpdate.IsZero()
+	ds := make([]DatasourceSettings, len(c.datasources))
+	copy(ds, c.datasources)
+	return ds, status
+}
+
+// getOrgs returns a copy of the cached organizations, without waiting for the
+// first refresh.
+func (c *datasourceCache) getOrgs() []Org {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if c.orgs == nil {
+		return nil
+	}
+	orgs := make([]Org, len(c.orgs))
+	copy(orgs, c.orgs)
+	return orgs
+}
+
+// find returns all cached datasources for which match returns true, without
+// waiting for the first refresh.
+func (c *datasourceCache) find(match func(DatasourceSettings) bool) []DatasourceSettings {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	var found []DatasourceSettings
+	for _, ds := range c.datasources {
+		if match(ds) {
+			found = append(found, ds)
+		}
+	}
+	return found
+}
+
+// close stops the background refresh.
+func (c *datasourceCache) close() {
+	close(c.stop)
+	<-c.done
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestDatasourceCacheGetDoesNotBlock(t *testing.T) {
+	unblock := make(chan struct{})
+	c := newDatasourceCache(slog.New(slog.DiscardHandler), time.Hour, func() ([]DatasourceSettings, []Org, error) {
+		<-unblock
+		return []DatasourceSettings{{ID: 1, Name: "prom"}}, nil, nil
+	})
+	defer c.close()
+
+	start := time.Now()
+	ds, status := c.get()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("get blocked for %v while Grafana didn't respond", elapsed)
+	}
+	if len(ds) != 0 || status.Error == "" || status.Stale || !status.LastUpdate.IsZero() {
+		t.Errorf("unexpected result before first refresh: %v, %+v", ds, status)
+	}
+
+	close(unblock)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ds, status = c.get()
+		if len(ds) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("datasources were not refreshed in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status.Error != "" || status.Stale || status.LastUpdate.IsZero() {
+		t.Errorf("unexpected status after refresh: %+v", status)
+	}
+}
+
+func TestDatasourceCacheRefresh(t *testing.T) {
+	var (
+		results = []error{errors.New("unavailable"), nil, errors.New("unavailable again")}
+		i       int
+	)
+	c := &datasourceCache{
+		logger: slog.New(slog.DiscardHandler),
+		fetch: func() ([]DatasourceSettings, []Org, error) {
+			err := results[i]
+			i++
+			if err != nil {
+				return nil, nil, err
+			}
+			return []DatasourceSettings{{ID: 1}}, []Org{{ID: 1, Name: "Main"}}, nil
+		},
+	}
+
+	for _, want := range []struct {
+		loaded bool
+		n      int
+		stale  bool
+		err    bool
+	}{
+		{loaded: false, n: 0, err: true},
+		{loaded: true, n: 1},
+		// Failed refreshes keep serving the last list.
+		{loaded: true, n: 1, stale: true, err: true},
+	} {
+		if got := c.refresh(); got != want.loaded {
+			t.Errorf("refresh %d: got loaded %t, want %t", i, got, want.loaded)
+		}
+		ds, status := c.get()
+		if len(ds) != want.n || status.Stale != want.stale || (status.Error != "") != want.err {
+			t.Errorf("refresh %d: unexpected result %v, %+v", i, ds, status)
+		}
+	}
+	if orgs := c.getOrgs(); len(orgs) != 1 {
+		t.Errorf("expected cached organizations, got %v", orgs)
+	}
+}
@@ -0,0 +1,435 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/promlens/pkg/audit"
+)
+
+// maxExportBodySize is the maximum size of a dashboard export request.
+const maxExportBodySize = 1 << 20
+
+// DashboardExport is a request to export PromLens queries to a Grafana
+// dashboard.
+type DashboardExport struct {
+	// DashboardUID selects the dashboard to update. If empty, a new dashboard
+	// with the given Title is created.
+	DashboardUID string `json:"dashboardUID"`
+	Title        string `json:"title"`
+	// PanelID selects the panel of the dashboard whose queries are replaced.
+	// If 0, a new panel with the given PanelTitle is added.
+	PanelID    int64  `json:"panelID"`
+	PanelTitle string `json:"panelTitle"`
+	// DatasourceID is the Grafana datasource that is selected in PromLens.
+	DatasourceID int64            `json:"datasourceID"`
+	Queries      []ExportQuery    `json:"queries"`
+	Visualizer   ExportVisualizer `json:"visualizer"`
+}
+
+// ExportQuery is a query to export.
+type ExportQuery struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
+
+// ExportVisualizer holds the visualizer settings of PromLens.
+type ExportVisualizer struct {
+	// ActiveTab is "table" for instant queries in a table panel, and graphs
+	// otherwise.
+	ActiveTab string `json:"activeTab"`
+	// EndTime is the end of the graph range in milliseconds since the epoch,
+	// or nil for the current time.
+	EndTime *int64 `json:"endTime"`
+	// Range is the graph range in milliseconds.
+	Range int64 `json:"range"`
+	// Resolution is the graph resolution in seconds, or nil for automatic
+	// resolution.
+	Resolution *float64 `json:"resolution"`
+	Stacked    bool     `json:"stacked"`
+}
+
+// DashboardExportResult describes an exported dashboard.
+type DashboardExportResult struct {
+	UID     string `json:"uid"`
+	URL     string `json:"url"`
+	Version int64  `json:"version"`
+	PanelID int64  `json:"panelID"`
+}
+
+var (
+	errPanelNotFound     = errors.New("panel not found")
+	errUnknownDatasource = errors.New("unknown datasource")
+	// errAmbiguousDatasource is returned for datasource UIDs or names that
+	// exist in more than one organization.
+	errAmbiguousDatasource = errors.New("ambiguous datasource")
+)
+
+// refID returns the Grafana query reference ID for the i-th query of a panel,
+// i.e. "A" to "Z", followed by "AA" and so on.
+func refID(i int) string {
+	id := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		id = string(rune('A'+(i-1)%26)) + id
+	}
+	return id
+}
+
+// exportTargets returns the panel targets for the exported queries.
+func exportTargets(e *DashboardExport, dsRef interface{}) []interface{} {
+	targets := make([]interface{}, 0, len(e.Queries))
+	for i, q := range e.Queries {
+		t := map[string]interface{}{
+			"refId":        refID(i),
+			"expr":         q.Expr,
+			"legendFormat": q.LegendFormat,
+			"datasource":   dsRef,
+		}
+		if e.Visualizer.ActiveTab == "table" {
+			t["instant"] = true
+			t["range"] = false
+			t["format"] = "table"
+		} else {
+			t["instant"] = false
+			t["range"] = true
+			if e.Visualizer.Resolution != nil {
+				t["interval"] = fmt.Sprintf("%gs", *e.Visualizer.Resolution)
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// childMap returns the map in m[key], adding an empty one if it's missing.
+func childMap(m map[string]interface{}, key string) map[string]interface{} {
+	c, ok := m[key].(map[string]interface{})
+	if !ok {
+		c = map[string]interface{}{}
+		m[key] = c
+	}
+	return c
+}
+
+// setStacking sets the stacking mode of a time series panel.
+func setStacking(panel map[string]interface{}, stacked bool) {
+	mode := "none"
+	if stacked {
+		mode = "normal"
+	}
+	custom := childMap(childMap(childMap(panel, "fieldConfig"), "defaults"), "custom")
+	childMap(custom, "stacking")["mode"] = mode
+}
+
+// forEachPanel calls f for every panel of a dashboard, including the panels
+// of collapsed rows, until f returns false.
+func forEachPanel(dashboard map[string]interface{}, f func(panel map[string]interface{}) bool) {
+	var walk func(panels []interface{}) bool
+	walk = func(panels []interface{}) bool {
+		for _, p := range panels {
+			panel, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if !f(panel) {
+				return false
+			}
+			if nested, ok := panel["panels"].([]interface{}); ok && !walk(nested) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(dashboard["panels"].([]interface{}))
+}
+
+func panelID(panel map[string]interface{}) int64 {
+	id, _ := panel["id"].(float64)
+	return int64(id)
+}
+
+// addPanel adds a panel with the exported queries below the other panels of
+// the dashboard and returns its ID.
+func addPanel(dashboard map[string]interface{}, e *DashboardExport, dsRef interface{}) int64 {
+	var maxID, bottom float64
+	forEachPanel(dashboard, func(panel map[string]interface{}) bool {
+		if id, _ := panel["id"].(float64); id > maxID {
+			maxID = id
+		}
+		if pos, ok := panel["gridPos"].(map[string]interface{}); ok {
+			y, _ := pos["y"].(float64)
+			h, _ := pos["h"].(float64)
+			if y+h > bottom {
+				bottom = y + h
+			}
+		}
+		return true
+	})
+
+	title := e.PanelTitle
+	if title == "" {
+		title = e.Queries[0].Expr
+	}
+	panelType := "timeseries"
+	if e.Visualizer.ActiveTab == "table" {
+		panelType = "table"
+	}
+	panel := map[string]interface{}{
+		"id":         maxID + 1,
+		"type":       panelType,
+		"title":      title,
+		"datasource": dsRef,
+		"targets":    exportTargets(e, dsRef),
+		"gridPos":    map[string]interface{}{"x": 0, "y": bottom, "w": 24, "h": 8},
+	}
+	if panelType == "timeseries" {
+		setStacking(panel, e.Visualizer.Stacked)
+	}
+	dashboard["panels"] = append(dashboard["panels"].([]interface{}), panel)
+	return int64(maxID + 1)
+}
+
+// replacePanelQueries replaces the queries and datasource of the panel with the
+// given ID, keeping its visualization.
+func replacePanelQueries(dashboard map[string]interface{}, id int64, e *DashboardExport, dsRef interface{}) error {
+	found := false
+	forEachPanel(dashboard, func(panel map[string]interface{}) bool {
+		if panelID(panel) != id {
+			return true
+		}
+		found = true
+		panel["datasource"] = dsRef
+		panel["targets"] = exportTargets(e, dsRef)
+		if e.PanelTitle != "" {
+			panel["title"] = e.PanelTitle
+		}
+		if panel["type"] == "timeseries" {
+			setStacking(panel, e.Visualizer.Stacked)
+		}
+		return false
+	})
+	if !found {
+		return fmt.Errorf("%w: %d", errPanelNotFound, id)
+	}
+	return nil
+}
+
+// relativeDuration formats a duration for a relative dashboard time range in
+// the largest unit that represents it exactly, e.g. "90m" rather than
+// "1h30m", which Grafana doesn't accept. Sub-second parts are rounded.
+func relativeDuration(d time.Duration) string {
+	s := max(int64(d.Round(time.Second)/time.Second), 1)
+	for _, u := range []struct {
+		secs int64
+		unit string
+	}{{24 * 60 * 60, "d"}, {60 * 60, "h"}, {60, "m"}} {
+		if s%u.secs == 0 {
+			return fmt.Sprintf("%d%s", s/u.secs, u.unit)
+		}
+	}
+	return fmt.Sprintf("%ds", s)
+}
+
+// newDashboard returns a dashboard whose time range matches the visualizer's.
+func newDashboard(e *DashboardExport) map[string]interface{} {
+	rng := time.Duration(e.Visualizer.Range) * time.Millisecond
+	if rng <= 0 {
+		rng = time.Hour
+	}
+	timeRange := map[string]interface{}{
+		"from": "now-" + relativeDuration(rng),
+		"to":   "now",
+	}
+	if e.Visualizer.EndTime != nil {
+		end := time.UnixMilli(*e.Visualizer.EndTime).UTC()
+		timeRange["from"] = end.Add(-rng).Format(time.RFC3339)
+		timeRange["to"] = end.Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"title":  e.Title,
+		"panels": []interface{}{},
+		"time":   timeRange,
+	}
+}
+
+// ExportDashboard creates or updates a dashboard with the given queries as the
+// user of the incoming request r.
+func (b *Backend) ExportDashboard(r *http.Request, e *DashboardExport) (*DashboardExportResult, error) {
+	if err := b.checkCredentials(r); err != nil {
+		return nil, err
+	}
+	ds, err := b.datasource(datasourceRef{id: e.DatasourceID})
+	if err != nil {
+		return nil, err
+	}
+	var orgID int64
+	if len(b.orgs) > 0 {
+		orgID = ds.OrgID
+	}
+	// Dashboards of Grafana versions before 8.3 refer to datasources by name.
+	var dsRef interface{} = map[string]interface{}{"type": ds.Type, "uid": ds.UID}
+	if ds.UID == "" {
+		dsRef = ds.Name
+	}
+
+	var (
+		dashboard map[string]interface{}
+		folderUID = b.dashboardFolderUID(orgID)
+		id        int64
+	)
+	if e.DashboardUID == "" {
+		dashboard = newDashboard(e)
+	} else {
+		var resp struct {
+			Dashboard map[string]interface{} `json:"dashboard"`
+			Meta      struct {
+				FolderUID string `json:"folderUid"`
+			} `json:"meta"`
+		}
+		if err := b.apiRequest(r.Context(), r, orgID, http.MethodGet, "/api/dashboards/uid/"+url.PathEscape(e.DashboardUID), nil, &resp); err != nil {
+			return nil, err
+		}
+		dashboard = resp.Dashboard
+		// Keep existing dashboards in their folder.
+		folderUID = resp.Meta.FolderUID
+	}
+	if _, ok := dashboard["panels"].([]interface{}); !ok {
+		dashboard["panels"] = []interface{}{}
+	}
+
+	if e.PanelID == 0 {
+		id = addPanel(dashboard, e, dsRef)
+	} else {
+		if err := replacePanelQueries(dashboard, e.PanelID, e, dsRef); err != nil {
+			return nil, err
+		}
+		id = e.PanelID
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"dashboard": dashboard,
+		"folderUid": folderUID,
+		// Fail if the dashboard was changed since it was fetched.
+		"overwrite": false,
+		"message":   "Exported from PromLens",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling dashboard: %w", err)
+	}
+	var resp struct {
+		UID     string `json:"uid"`
+		URL     string `json:"url"`
+		Version int64  `json:"version"`
+	}
+	if err := b.apiRequest(r.Context(), r, orgID, http.MethodPost, "/api/dashboards/db", bytes.NewReader(body), &resp); err != nil {
+		return nil, err
+	}
+
+	// Grafana returns the dashboard's path, including Grafana's path prefix.
+	dashboardURL := resp.URL
+	if base, err := url.Parse(b.url); err == nil {
+		dashboardURL = base.ResolveReference(&url.URL{Path: resp.URL}).String()
+	}
+	return &DashboardExportResult{UID: resp.UID, URL: dashboardURL, Version: resp.Version, PanelID: id}, nil
+}
+
+// dashboardFolderUID returns the folder for new dashboards in the organization
+// with the given ID.
+func (b *Backend) dashboardFolderUID(orgID int64) string {
+	for _, o := range b.orgs {
+		if o.ID == orgID && o.DashboardFolderUID != "" {
+			return o.DashboardFolderUID
+		}
+	}
+	return b.folderUID
+}
+
+// HandleDashboardExport creates or updates a dashboard with the PromLens
+// queries in the JSON-encoded DashboardExport in the request body.
+func (b *Backend) HandleDashboardExport(al *audit.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !b.dashboardExport {
+			http.Error(w, "Dashboard export is not enabled", http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid HTTP method, use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		// Only accept JSON bodies, which browsers can't send cross-site
+		// without a CORS preflight.
+		if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+			http.Error(w, "Invalid content type, use application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var e DashboardExport
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxExportBodySize)).Decode(&e); err != nil {
+			http.Error(w, fmt.Sprintf("Error unmarshaling dashboard export: %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(e.Queries) == 0 {
+			http.Error(w, "No queries to export", http.StatusBadRequest)
+			return
+		}
+		exprs := make([]string, 0, len(e.Queries))
+		for _, q := range e.Queries {
+			if strings.TrimSpace(q.Expr) == "" {
+				http.Error(w, "Queries must not be empty", http.StatusBadRequest)
+				return
+			}
+			exprs = append(exprs, q.Expr)
+		}
+		if e.DashboardUID == "" && e.Title == "" {
+			http.Error(w, "A title is required for new dashboards", http.StatusBadRequest)
+			return
+		}
+		if e.DashboardUID == "" && e.PanelID != 0 {
+			http.Error(w, "A panel can only be selected in an existing dashboard", http.StatusBadRequest)
+			return
+		}
+
+		res, err := b.ExportDashboard(r, &e)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error exporting dashboard: %v", err), apiErrorStatus(err))
+			return
+		}
+		if al.Enabled() {
+			al.Log(r, audit.Event{
+				Action:       audit.ActionExportDashboard,
+				DatasourceID: e.DatasourceID,
+				Dashboard:    res.UID,
+				Query:        strings.Join(exprs, "\n"),
+			})
+		}
+
+		buf, err := json.Marshal(res)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error marshaling dashboard export result: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(buf)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/promlens/pkg/audit"
+)
+
+func TestRelativeDuration(t *testing.T) {
+	for _, tc := range []struct {
+		in   time.Duration
+		want string
+	}{
+		{in: time.Hour, want: "1h"},
+		{in: 90 * time.Minute, want: "90m"},
+		{in: 48 * time.Hour, want: "2d"},
+		{in: 25 * time.Hour, want: "25h"},
+		{in: 90 * time.Second, want: "90s"},
+		{in: 1500 * time.Millisecond, want: "2s"},
+		{in: time.Millisecond, want: "1s"},
+	} {
+		if got := relativeDuration(tc.in); got != tc.want {
+			t.Errorf("relativeDuration(%v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRefID(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := refID(i); got != want {
+			t.Errorf("refID(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestHandleDashboardExportRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		enabled     bool
+		method      string
+		contentType string
+		want        int
+	}{
+		{name: "disabled", enabled: false, method: http.MethodPost, contentType: "application/json", want: http.StatusNotFound},
+		{name: "GET", enabled: true, method: http.MethodGet, contentType: "application/json", want: http.StatusMethodNotAllowed},
+		{name: "form body", enabled: true, method: http.MethodPost, contentType: "application/x-www-form-urlencoded", want: http.StatusUnsupportedMediaType},
+		{name: "text body", enabled: true, method: http.MethodPost, contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+		{name: "no queries", enabled: true, method: http.MethodPost, contentType: "application/json; charset=utf-8", want: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Backend{dashboardExport: tc.enabled}
+			req := httptest.NewRequest(tc.method, "/api/dashboard_export", strings.NewReader(`{"title":"test"}`))
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+			b.HandleDashboardExport(audit.New())(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
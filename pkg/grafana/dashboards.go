@@ -0,0 +1,413 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Dashboard holds the PromQL queries and template variables of a Grafana
+// dashboard.
+type Dashboard struct {
+	UID       string              `json:"uid"`
+	Title     string              `json:"title"`
+	OrgID     int64               `json:"orgID"`
+	Panels    []DashboardPanel    `json:"panels"`
+	Variables []DashboardVariable `json:"variables"`
+}
+
+// DashboardPanel is a panel of a dashboard with at least one PromQL target.
+type DashboardPanel struct {
+	ID      int64             `json:"id"`
+	Title   string            `json:"title"`
+	Type    string            `json:"type"`
+	Targets []DashboardTarget `json:"targets"`
+}
+
+// DashboardTarget is a PromQL query of a panel.
+type DashboardTarget struct {
+	RefID        string              `json:"refId"`
+	Expr         string              `json:"expr"`
+	LegendFormat string              `json:"legendFormat,omitempty"`
+	Hide         bool                `json:"hide"`
+	Datasource   DashboardDatasource `json:"datasource"`
+}
+
+// DashboardDatasource is the datasource of a target.
+type DashboardDatasource struct {
+	// Ref is the datasource reference as it appears in the dashboard: a name,
+	// a UID, or a template variable. It is empty for the default datasource.
+	Ref string `json:"ref"`
+	// ID and Name are only set if the reference could be resolved to one of
+	// the accepted datasources.
+	ID   int64  `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// DashboardVariable is a template variable of a dashboard.
+type DashboardVariable struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Label      string   `json:"label,omitempty"`
+	Query      string   `json:"query,omitempty"`
+	Current    []string `json:"current"`
+	Multi      bool     `json:"multi"`
+	IncludeAll bool     `json:"includeAll"`
+}
+
+// Placeholders for special datasources in dashboards.
+const (
+	mixedDatasource   = "-- Mixed --"
+	defaultDatasource = "default"
+)
+
+// dashboardJSON is the part of Grafana's dashboard model that is needed to
+// extract queries.
+type dashboardJSON struct {
+	UID    string      `json:"uid"`
+	Title  string      `json:"title"`
+	Panels []panelJSON `json:"panels"`
+	// Rows contain the panels of dashboards with the old schema.
+	Rows []struct {
+		Panels []panelJSON `json:"panels"`
+	} `json:"rows"`
+	Templating struct {
+		List []variableJSON `json:"list"`
+	} `json:"templating"`
+}
+
+type panelJSON struct {
+	ID         int64           `json:"id"`
+	Title      string          `json:"title"`
+	Type       string          `json:"type"`
+	Datasource json.RawMessage `json:"datasource"`
+	Targets    []struct {
+		RefID        string          `json:"refId"`
+		Expr         string          `json:"expr"`
+		LegendFormat string          `json:"legendFormat"`
+		Hide         bool            `json:"hide"`
+		Datasource   json.RawMessage `json:"datasource"`
+	} `json:"targets"`
+	// Panels are the panels of a collapsed row.
+	Panels []panelJSON `json:"panels"`
+}
+
+type variableJSON struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Label      string          `json:"label"`
+	Query      json.RawMessage `json:"query"`
+	Multi      bool            `json:"multi"`
+	IncludeAll bool            `json:"includeAll"`
+	Current    struct {
+		Value json.RawMessage `json:"value"`
+	} `json:"current"`
+}
+
+// datasourceRefJSON reads a datasource reference, which is either a name (or
+// UID) string or, since Grafana 8.3, an object with the type and UID.
+type datasourceRefJSON struct {
+	Type string
+	Ref  string
+}
+
+func (d *datasourceRefJSON) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		d.Ref = s
+		return nil
+	}
+	var o struct {
+		Type string `json:"type"`
+		UID  string `json:"uid"`
+	}
+	if err := json.Unmarshal(b, &o); err != nil {
+		return err
+	}
+	d.Type, d.Ref = o.Type, o.UID
+	return nil
+}
+
+func parseDatasourceRef(raw json.RawMessage) datasourceRefJSON {
+	var d datasourceRefJSON
+	if len(raw) != 0 {
+		// Treat unknown formats like the default datasource.
+		_ = json.Unmarshal(raw, &d)
+	}
+	return d
+}
+
+// stringOrList reads a string or a list of strings, as used for the current
+// values of template variables.
+func stringOrList(raw json.RawMessage) []string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []string{s}
+	}
+	var l []string
+	if err := json.Unmarshal(raw, &l); err == nil {
+		return l
+	}
+	return []string{}
+}
+
+// variableQuery reads the query of a template variable, which is either a
+// string or an object with a "query" field.
+func variableQuery(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var o struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(raw, &o); err == nil {
+		return o.Query
+	}
+	return ""
+}
+
+// ParseDashboardURL returns the UID and the organization ID (or 0 if it isn't
+// set) of a dashboard URL like "https://grafana.example.com/d/<uid>/<slug>?orgId=1".
+func ParseDashboardURL(s string) (uid string, orgID int64, err error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid dashboard URL: %w", err)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, seg := range segments {
+		if (seg == "d" || seg == "d-solo") && i+1 < len(segments) && segments[i+1] != "" {
+			uid = segments[i+1]
+			break
+		}
+	}
+	if uid == "" {
+		return "", 0, fmt.Errorf("no dashboard UID in URL %q", s)
+	}
+	if o := u.Query().Get("orgId"); o != "" {
+		orgID, err = strconv.ParseInt(o, 10, 64)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid organization ID %q in dashboard URL", o)
+		}
+	}
+	return uid, orgID, nil
+}
+
+// GetDashboard fetches the dashboard with the given UID from the organization
+// with the given ID (or the API token's organization, if 0) as the user of the
+// incoming request r, and extracts its PromQL queries.
+func (b *Backend) GetDashboard(r *http.Request, orgID int64, uid string) (*Dashboard, error) {
+	if err := b.checkCredentials(r); err != nil {
+		return nil, err
+	}
+	if err := b.checkOrg(orgID); err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Dashboard dashboardJSON `json:"dashboard"`
+	}
+	if err := b.apiRequest(r.Context(), r, orgID, http.MethodGet, "/api/dashboards/uid/"+url.PathEscape(uid), nil, &resp); err != nil {
+		return nil, err
+	}
+	return b.extractDashboard(orgID, &resp.Dashboard), nil
+}
+
+func (b *Backend) extractDashboard(orgID int64, dj *dashboardJSON) *Dashboard {
+	d := &Dashboard{
+		UID:       dj.UID,
+		Title:     dj.Title,
+		OrgID:     orgID,
+		Panels:    []DashboardPanel{},
+		Variables: make([]DashboardVariable, 0, len(dj.Templating.List)),
+	}
+
+	// Datasource variables can be resolved through their current value.
+	dsVars := map[string]string{}
+	for _, v := range dj.Templating.List {
+		current := stringOrList(v.Current.Value)
+		d.Variables = append(d.Variables, DashboardVariable{
+			Name:       v.Name,
+			Type:       v.Type,
+			Label:      v.Label,
+			Query:      variableQuery(v.Query),
+			Current:    current,
+			Multi:      v.Multi,
+			IncludeAll: v.IncludeAll,
+		})
+		if v.Type == "datasource" && len(current) == 1 {
+			dsVars[v.Name] = current[0]
+		}
+	}
+
+	panels := dj.Panels
+	for _, row := range dj.Rows {
+		panels = append(panels, row.Panels...)
+	}
+	var addPanels func(panels []panelJSON)
+	addPanels = func(panels []panelJSON) {
+		for _, p := range panels {
+			addPanels(p.Panels)
+
+			panelDS := parseDatasourceRef(p.Datasource)
+			dp := DashboardPanel{ID: p.ID, Title: p.Title, Type: p.Type}
+			for _, t := range p.Targets {
+				if t.Expr == "" {
+					continue
+				}
+				ds := panelDS
+				if ds.Ref == mixedDatasource || len(t.Datasource) != 0 {
+					ds = parseDatasourceRef(t.Datasource)
+				}
+				// Skip queries of other datasource types, such as Loki.
+				if _, ok := b.types[ds.Type]; ds.Type != "" && !ok {
+					continue
+				}
+				dp.Targets = append(dp.Targets, DashboardTarget{
+					RefID:        t.RefID,
+					Expr:         t.Expr,
+					LegendFormat: t.LegendFormat,
+					Hide:         t.Hide,
+					Datasource:   b.resolveDashboardDatasource(orgID, ds.Ref, dsVars),
+				})
+			}
+			if len(dp.Targets) > 0 {
+				d.Panels = append(d.Panels, dp)
+			}
+		}
+	}
+	addPanels(panels)
+	return d
+}
+
+// resolveDashboardDatasource looks up the datasource that a dashboard refers
+// to by UID or name, in the organization with the given ID.
+func (b *Backend) resolveDashboardDatasource(orgID int64, ref string, dsVars map[string]string) DashboardDatasource {
+	dd := DashboardDatasource{Ref: ref}
+	if ref == defaultDatasource {
+		ref = ""
+	}
+	if name, ok := strings.CutPrefix(ref, "$"); ok {
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "{"), "}")
+		ref = dsVars[name]
+		if ref == "" {
+			return dd
+		}
+	}
+
+	inOrg := func(ds DatasourceSettings) bool {
+		return orgID == 0 || ds.OrgID == orgID
+	}
+	var found []DatasourceSettings
+	if ref == "" {
+		found = b.cache.find(func(ds DatasourceSettings) bool {
+			return inOrg(ds) && ds.IsDefault
+		})
+	} else {
+		found = b.cache.find(func(ds DatasourceSettings) bool {
+			return inOrg(ds) && ds.UID == ref
+		})
+		if len(found) == 0 {
+			found = b.cache.find(func(ds DatasourceSettings) bool {
+				return inOrg(ds) && ds.Name == ref
+			})
+		}
+	}
+	// Without an organization, a reference that matches datasources in several
+	// organizations is left unresolved rather than guessing one of them.
+	if len(found) == 1 {
+		dd.ID, dd.Name = found[0].ID, found[0].Name
+	}
+	return dd
+}
+
+// HandleDashboardImport returns the PromQL queries and template variables of
+// a dashboard, which is selected by the "uid" or "url" parameter.
+func (b *Backend) HandleDashboardImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The API token may read dashboards that the user can't, so only
+		// import with it if that was explicitly allowed.
+		if b.userAuth.Mode == UserAuthToken && !b.dashboardImport {
+			http.Error(w, "Dashboard import is not enabled", http.StatusNotFound)
+			return
+		}
+		uid := r.FormValue("uid")
+		var orgID int64
+		if u := r.FormValue("url"); u != "" {
+			var err error
+			uid, orgID, err = ParseDashboardURL(u)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if uid == "" {
+			http.Error(w, "One of the \"uid\" or \"url\" parameters must be set", http.StatusBadRequest)
+			return
+		}
+		if o := r.FormValue("org_id"); o != "" {
+			var err error
+			orgID, err = strconv.ParseInt(o, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid organization ID %q", o), http.StatusBadRequest)
+				return
+			}
+		}
+
+		d, err := b.GetDashboard(r, orgID, uid)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error fetching dashboard: %v", err), apiErrorStatus(err))
+			return
+		}
+
+		buf, err := json.Marshal(d)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error marshaling dashboard: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(buf)
+	}
+}
+
+// apiErrorStatus returns the HTTP status to respond with for an error of a
+// request to the Grafana API.
+func apiErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNoUserCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrUnknownOrg), errors.Is(err, errPanelNotFound), errors.Is(err, errUnknownDatasource), errors.Is(err, errAmbiguousDatasource):
+		return http.StatusBadRequest
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusNotFound:
+			return http.StatusNotFound
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return http.StatusForbidden
+		case http.StatusBadRequest:
+			return http.StatusBadRequest
+		case http.StatusPreconditionFailed:
+			// The dashboard was changed concurrently.
+			return http.StatusConflict
+		}
+	}
+	return http.StatusBadGateway
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseDashboardURL(t *testing.T) {
+	for _, tc := range []struct {
+		url     string
+		uid     string
+		orgID   int64
+		wantErr bool
+	}{
+		{url: "https://grafana.example.com/d/abc123/my-dashboard", uid: "abc123"},
+		{url: "https://grafana.example.com/d/abc123/my-dashboard?orgId=2&from=now-1h", uid: "abc123", orgID: 2},
+		{url: "https://example.com/grafana/d/abc123", uid: "abc123"},
+		{url: "https://grafana.example.com/d-solo/abc123/my-dashboard?orgId=1&panelId=4", uid: "abc123", orgID: 1},
+		{url: "/d/abc123/", uid: "abc123"},
+		{url: "https://grafana.example.com/dashboards", wantErr: true},
+		{url: "https://grafana.example.com/d/", wantErr: true},
+		{url: "https://grafana.example.com/d/abc123?orgId=main", wantErr: true},
+		{url: "://invalid", wantErr: true},
+	} {
+		uid, orgID, err := ParseDashboardURL(tc.url)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseDashboardURL(%q): expected error, got UID %q", tc.url, uid)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDashboardURL(%q): unexpected error: %v", tc.url, err)
+			continue
+		}
+		if uid != tc.uid || orgID != tc.orgID {
+			t.Errorf("ParseDashboardURL(%q) = (%q, %d), want (%q, %d)", tc.url, uid, orgID, tc.uid, tc.orgID)
+		}
+	}
+}
+
+func TestHandleDashboardImportDisabled(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		mode    string
+		enabled bool
+		want    int
+	}{
+		// Requests that pass the check fail later on the missing UID.
+		{name: "token mode", mode: UserAuthToken, want: http.StatusNotFound},
+		{name: "token mode with opt-in", mode: UserAuthToken, enabled: true, want: http.StatusBadRequest},
+		{name: "session mode", mode: UserAuthSession, want: http.StatusBadRequest},
+		{name: "auth proxy mode", mode: UserAuthProxy, want: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Backend{userAuth: UserAuthConfig{Mode: tc.mode}, dashboardImport: tc.enabled}
+			rec := httptest.NewRecorder()
+			b.HandleDashboardImport()(rec, httptest.NewRequest(http.MethodGet, "/api/dashboard_import", nil))
+			if rec.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
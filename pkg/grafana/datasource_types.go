@@ -0,0 +1,145 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DatasourceTypeConfig configures a Grafana datasource type that speaks the
+// Prometheus query API, along with any headers that datasources of this type
+// need on proxied requests.
+type DatasourceTypeConfig struct {
+	Type string `yaml:"type"`
+	// Headers are set on every proxied request.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// JSONDataHeaders maps header names to fields of the datasource's
+	// jsonData settings, whose values are set in the headers of proxied
+	// requests (e.g. a tenant ID as the X-Scope-OrgID header).
+	JSONDataHeaders map[string]string `yaml:"json_data_headers,omitempty"`
+}
+
+// DefaultDatasourceTypes are the datasource types that are used if none are
+// configured.
+var DefaultDatasourceTypes = []DatasourceTypeConfig{{Type: "prometheus"}}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *DatasourceTypeConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain DatasourceTypeConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Type == "" {
+		return errors.New("missing datasource type")
+	}
+	for name := range c.Headers {
+		if _, ok := c.JSONDataHeaders[name]; ok {
+			return fmt.Errorf("header %q is set in both headers and json_data_headers of datasource type %q", name, c.Type)
+		}
+	}
+	return nil
+}
+
+func datasourceTypesMap(cfgs []DatasourceTypeConfig) (map[string]DatasourceTypeConfig, error) {
+	if len(cfgs) == 0 {
+		cfgs = DefaultDatasourceTypes
+	}
+	types := make(map[string]DatasourceTypeConfig, len(cfgs))
+	for _, c := range cfgs {
+		if _, ok := types[c.Type]; ok {
+			return nil, fmt.Errorf("duplicate datasource type %q", c.Type)
+		}
+		types[c.Type] = c
+	}
+	return types, nil
+}
+
+// setDatasourceHeaders sets the headers that the datasource's type requires.
+func (b *Backend) setDatasourceHeaders(h http.Header, ds DatasourceSettings) {
+	tc := b.types[ds.Type]
+	for name, value := range tc.Headers {
+		h.Set(name, value)
+	}
+	for name, field := range tc.JSONDataHeaders {
+		// Don't let clients choose the value if the field is missing.
+		h.Del(name)
+		switch v := ds.JSONData[field].(type) {
+		case string:
+			h.Set(name, v)
+		case float64:
+			h.Set(name, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			h.Set(name, strconv.FormatBool(v))
+		}
+	}
+}
+
+// datasourceRef identifies a datasource in a datasource proxy path.
+type datasourceRef struct {
+	id  int64
+	uid string
+}
+
+func (ref datasourceRef) matches(ds DatasourceSettings) bool {
+	if ref.uid != "" {
+		return ds.UID == ref.uid
+	}
+	return ds.ID == ref.id
+}
+
+// parseProxyPath splits the path below the datasource proxy prefix, which is
+// either "<id>/<path>" or "uid/<uid>/<path>", into the datasource reference
+// and the path to proxy to.
+func parseProxyPath(path string) (datasourceRef, string, error) {
+	ds, rest, _ := strings.Cut(path, "/")
+	if ds == "uid" {
+		uid, rest, _ := strings.Cut(rest, "/")
+		if uid == "" {
+			return datasourceRef{}, "", errors.New("missing datasource UID")
+		}
+		return datasourceRef{uid: uid}, "/" + rest, nil
+	}
+	id, err := strconv.ParseInt(ds, 10, 64)
+	if err != nil {
+		return datasourceRef{}, "", fmt.Errorf("invalid datasource ID %q", ds)
+	}
+	return datasourceRef{id: id}, "/" + rest, nil
+}
+
+func (ref datasourceRef) String() string {
+	if ref.uid != "" {
+		return fmt.Sprintf("UID %q", ref.uid)
+	}
+	return fmt.Sprintf("ID %d", ref.id)
+}
+
+// datasource returns the cached datasource that ref refers to. Since UIDs are
+// only unique within an organization, a UID that exists in several of the
+// configured organizations is rejected instead of picking one of them, which
+// would send the request with that organization's credentials.
+func (b *Backend) datasource(ref datasourceRef) (DatasourceSettings, error) {
+	found := b.cache.find(ref.matches)
+	switch len(found) {
+	case 0:
+		return DatasourceSettings{}, fmt.Errorf("%w %s", errUnknownDatasource, ref)
+	case 1:
+		return found[0], nil
+	default:
+		return DatasourceSettings{}, fmt.Errorf("%w: %s exists in several organizations", errAmbiguousDatasource, ref)
+	}
+}
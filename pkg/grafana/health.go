@@ -0,0 +1,272 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxConcurrentHealthChecks limits the number of datasources that are probed
+// at the same time.
+const maxConcurrentHealthChecks = 10
+
+// healthRetryInterval is the interval at which health checks are retried as
+// long as the datasources haven't been fetched from Grafana.
+const healthRetryInterval = 5 * time.Second
+
+var (
+	datasourceUpDesc = prometheus.NewDesc(
+		"promlens_grafana_datasource_up",
+		"Whether the last health check of a Grafana datasource through the Grafana datasource proxy succeeded.",
+		[]string{"datasource_id", "datasource"}, nil,
+	)
+	datasourceBuildInfoDesc = prometheus.NewDesc(
+		"promlens_grafana_datasource_build_info",
+		"A metric with a constant '1' value labeled by the Prometheus version that a healthy Grafana datasource reports.",
+		[]string{"datasource_id", "datasource", "version"}, nil,
+	)
+)
+
+// healthCollector exports the health check results of the most recently
+// started health checker that is still running. After a reload, the checker
+// of the replaced backend may keep running until its requests are done, but
+// its results are no longer exported.
+type healthCollector struct {
+	mtx sync.Mutex
+	// checkers are the running health checkers in the order they started.
+	checkers []*healthChecker
+}
+
+var activeHealth = &healthCollector{}
+
+func init() {
+	prometheus.MustRegister(activeHealth)
+}
+
+// Describe implements prometheus.Collector.
+func (c *healthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- datasourceUpDesc
+	ch <- datasourceBuildInfoDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *healthCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mtx.Lock()
+	var h *healthChecker
+	if len(c.checkers) > 0 {
+		h = c.checkers[len(c.checkers)-1]
+	}
+	c.mtx.Unlock()
+	if h != nil {
+		h.collect(ch)
+	}
+}
+
+func (c *healthCollector) add(h *healthChecker) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.checkers = append(c.checkers, h)
+}
+
+func (c *healthCollector) remove(h *healthChecker) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.checkers = slices.DeleteFunc(c.checkers, func(other *healthChecker) bool { return other == h })
+}
+
+// DatasourceHealth is the result of the last health check of a datasource.
+type DatasourceHealth struct {
+	Up bool `json:"up"`
+	// Version is the Prometheus version that the datasource reports.
+	Version   string    `json:"version,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	LastCheck time.Time `json:"lastCheck"`
+}
+
+// healthChecker periodically probes the build information endpoint of every
+// cached datasource through the Grafana datasource proxy.
+type healthChecker struct {
+	b        *Backend
+	logger   *slog.Logger
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+
+	mtx     sync.RWMutex
+	results map[int64]DatasourceHealth
+	// names are the names of the checked datasources by ID.
+	names map[int64]string
+}
+
+func newHealthChecker(b *Backend, logger *slog.Logger, interval time.Duration) *healthChecker {
+	h := &healthChecker{
+		b:        b,
+		logger:   logger,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		results:  map[int64]DatasourceHealth{},
+		names:    map[int64]string{},
+	}
+	activeHealth.add(h)
+	go h.run()
+	return h
+}
+
+func (h *healthChecker) run() {
+	defer close(h.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		wait := h.interval
+		if !h.checkAll(ctx) {
+			wait = min(wait, healthRetryInterval)
+		}
+		select {
+		case <-time.After(wait):
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+// checkAll probes all cached datasources and replaces the previous results.
+// It returns false if the datasources haven't been fetched yet.
+func (h *healthChecker) checkAll(ctx context.Context) bool {
+	datasources, status := h.b.cache.get()
+	if ctx.Err() != nil || status.LastUpdate.IsZero() {
+		return false
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mtx     sync.Mutex
+		sem     = make(chan struct{}, maxConcurrentHealthChecks)
+		results = make(map[int64]DatasourceHealth, len(datasources))
+		names   = make(map[int64]string, len(datasources))
+	)
+	for _, ds := range datasources {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			res := DatasourceHealth{LastCheck: time.Now()}
+			version, err := h.b.checkDatasource(ctx, ds)
+			if err != nil {
+				res.Error = err.Error()
+				h.logger.Debug("Grafana datasource health check failed", "datasource_id", ds.ID, "datasource", ds.Name, "err", err)
+			} else {
+				res.Up, res.Version = true, version
+			}
+
+			mtx.Lock()
+			results[ds.ID] = res
+			mtx.Unlock()
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return false
+	}
+	for _, ds := range datasources {
+		names[ds.ID] = ds.Name
+	}
+
+	h.mtx.Lock()
+	h.results = results
+	h.names = names
+	h.mtx.Unlock()
+	return true
+}
+
+// collect exports the last health check results as metrics.
+func (h *healthChecker) collect(ch chan<- prometheus.Metric) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	for id, res := range h.results {
+		idLabel := strconv.FormatInt(id, 10)
+		up := 0.0
+		if res.Up {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(datasourceUpDesc, prometheus.GaugeValue, up, idLabel, h.names[id])
+		if res.Up && res.Version != "" {
+			ch <- prometheus.MustNewConstMetric(datasourceBuildInfoDesc, prometheus.GaugeValue, 1, idLabel, h.names[id], res.Version)
+		}
+	}
+}
+
+// get returns the last health check result of the datasource with the given ID.
+func (h *healthChecker) get(id int64) (DatasourceHealth, bool) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	res, ok := h.results[id]
+	return res, ok
+}
+
+// close stops the health checks and their metrics.
+func (h *healthChecker) close() {
+	activeHealth.remove(h)
+	close(h.stop)
+	<-h.done
+}
+
+// checkDatasource requests the build information of a datasource through the
+// Grafana datasource proxy and returns its Prometheus version.
+func (b *Backend) checkDatasource(ctx context.Context, ds DatasourceSettings) (string, error) {
+	var orgID int64
+	if len(b.orgs) > 0 {
+		orgID = ds.OrgID
+	}
+	req, err := b.newAPIRequest(ctx, nil, orgID, http.MethodGet, fmt.Sprintf("/api/datasources/proxy/%d/api/v1/status/buildinfo", ds.ID), nil)
+	if err != nil {
+		return "", err
+	}
+	b.setDatasourceHeaders(req.Header, ds)
+
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			Version string `json:"version"`
+		} `json:"data"`
+	}
+	if err := doAPIRequest(req, &resp); err != nil {
+		return "", err
+	}
+	if resp.Status != "success" {
+		return "", fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return resp.Data.Version, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gatherHealth returns the health metrics of the collector as sorted
+// "name{labels} value" lines.
+func gatherHealth(t *testing.T, c *healthCollector) []string {
+	t.Helper()
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+			}
+			lines = append(lines, fmt.Sprintf("%s{%s} %g", mf.GetName(), strings.Join(labels, ","), m.GetGauge().GetValue()))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestHealthCheck(t *testing.T) {
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/datasources/proxy/1/api/v1/status/buildinfo":
+			fmt.Fprint(w, `{"status":"success","data":{"version":"2.55.1"}}`)
+		default:
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}
+	}))
+	defer grafana.Close()
+
+	b := &Backend{url: grafana.URL, authToken: "token"}
+	b.cache = &datasourceCache{
+		datasources: []DatasourceSettings{{ID: 1, Name: "up"}, {ID: 2, Name: "down"}},
+		lastUpdate:  time.Now(),
+	}
+	h := &healthChecker{b: b, logger: slog.New(slog.DiscardHandler)}
+	if !h.checkAll(context.Background()) {
+		t.Fatal("expected health checks to run")
+	}
+
+	if res, ok := h.get(1); !ok || !res.Up || res.Version != "2.55.1" {
+		t.Errorf("unexpected result for healthy datasource: %+v", res)
+	}
+	if res, ok := h.get(2); !ok || res.Up || res.Error == "" {
+		t.Errorf("unexpected result for failing datasource: %+v", res)
+	}
+
+	c := &healthCollector{}
+	c.add(h)
+	got := gatherHealth(t, c)
+	want := []string{
+		`promlens_grafana_datasource_build_info{datasource="up",datasource_id="1",version="2.55.1"} 1`,
+		`promlens_grafana_datasource_up{datasource="down",datasource_id="2"} 0`,
+		`promlens_grafana_datasource_up{datasource="up",datasource_id="1"} 1`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected metrics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHealthCheckWaitsForDatasources(t *testing.T) {
+	h := &healthChecker{b: &Backend{cache: &datasourceCache{}}, logger: slog.New(slog.DiscardHandler)}
+	if h.checkAll(context.Background()) {
+		t.Error("expected health checks to wait until the datasources were fetched")
+	}
+}
+
+func TestHealthCollectorExportsNewestChecker(t *testing.T) {
+	checker := func(name string) *healthChecker {
+		return &healthChecker{
+			results: map[int64]DatasourceHealth{1: {Up: true}},
+			names:   map[int64]string{1: name},
+		}
+	}
+	oldChecker, newChecker := checker("old"), checker("new")
+
+	c := &healthCollector{}
+	c.add(oldChecker)
+	c.add(newChecker)
+	for _, tc := range []struct {
+		remove *healthChecker
+		want   string
+	}{
+		{want: "new"},
+		// A replaced backend that is closed later doesn't affect the metrics.
+		{remove: oldChecker, want: "new"},
+		{remove: newChecker, want: ""},
+	} {
+		if tc.remove != nil {
+			c.remove(tc.remove)
+		}
+		got := gatherHealth(t, c)
+		if tc.want == "" {
+			if len(got) != 0 {
+				t.Errorf("expected no metrics, got %v", got)
+			}
+			continue
+		}
+		if len(got) != 1 || !strings.Contains(got[0], `datasource="`+tc.want+`"`) {
+			t.Errorf("expected metrics of %q checker, got %v", tc.want, got)
+		}
+	}
+
+	// Closing a new backend after a failed reload restores the previous one.
+	c = &healthCollector{}
+	c.add(oldChecker)
+	c.add(newChecker)
+	c.remove(newChecker)
+	if got := gatherHealth(t, c); len(got) != 1 || !strings.Contains(got[0], `datasource="old"`) {
+		t.Errorf("expected metrics of old checker, got %v", got)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// orgIDHeader selects the organization of a Grafana API request.
+const orgIDHeader = "X-Grafana-Org-Id"
+
+// apiTimeout is the timeout for requests to the Grafana API.
+// TODO: Make timeout configurable.
+const apiTimeout = 5 * time.Second
+
+// Org is a Grafana organization.
+type Org struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// OrgOptions configures access to a Grafana organization.
+type OrgOptions struct {
+	ID int64
+	// AuthToken is a token of the organization. If empty, the backend's token
+	// is used along with the X-Grafana-Org-Id header, which requires it to
+	// have access to all organizations.
+	AuthToken string
+	// DashboardFolderUID overrides the folder that new dashboards in the
+	// organization are exported to.
+	DashboardFolderUID string
+}
+
+// ErrUnknownOrg is returned for requests to organizations that aren't
+// configured.
+var ErrUnknownOrg = errors.New("unknown Grafana organization")
+
+// checkOrg returns ErrUnknownOrg if organizations are configured and the
+// given organization is not one of them.
+func (b *Backend) checkOrg(orgID int64) error {
+	if len(b.orgs) == 0 || orgID == 0 {
+		return nil
+	}
+	for _, o := range b.orgs {
+		if o.ID == orgID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %d", ErrUnknownOrg, orgID)
+}
+
+// token returns the API token to use for the organization with the given ID.
+// An ID of 0 refers to the token's own organization.
+func (b *Backend) token(orgID int64) string {
+	for _, o := range b.orgs {
+		if o.ID == orgID && o.AuthToken != "" {
+			return o.AuthToken
+		}
+	}
+	return b.authToken
+}
+
+// setOrg selects the organization of an outgoing request to Grafana. Clients
+// can't select an organization themselves.
+func (b *Backend) setOrg(h http.Header, orgID int64) {
+	h.Del(orgIDHeader)
+	if len(b.orgs) > 0 && orgID != 0 {
+		h.Set(orgIDHeader, strconv.FormatInt(orgID, 10))
+	}
+}
+
+// APIError is returned for unsuccessful responses of the Grafana API.
+type APIError struct {
+	Path       string
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bad response status for %s: %v: %s", e.Path, e.Status, e.Message)
+}
+
+// apiRequest sends a request to the Grafana API for the organization with the
+// given ID and decodes the JSON response into v. If in is nil, the request is
+// sent with the API token, otherwise as the user of the incoming request in.
+func (b *Backend) apiRequest(ctx context.Context, in *http.Request, orgID int64, method, path string, body io.Reader, v interface{}) error {
+	req, err := b.newAPIRequest(ctx, in, orgID, method, path, body)
+	if err != nil {
+		return err
+	}
+	return doAPIRequest(req, v)
+}
+
+// newAPIRequest creates a request to the Grafana API with credentials like
+// apiRequest.
+func (b *Backend) newAPIRequest(ctx context.Context, in *http.Request, orgID int64, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, singleJoiningSlash(b.url, path), body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if in == nil {
+		req.Header.Set("Authorization", "Bearer "+b.token(orgID))
+		b.setOrg(req.Header, orgID)
+	} else {
+		b.setCredentials(req, in, orgID)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// doAPIRequest sends a request to the Grafana API and decodes the JSON
+// response into v.
+func doAPIRequest(req *http.Request, v interface{}) error {
+	path := req.URL.Path
+	c := &http.Client{Timeout: apiTimeout}
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("error requesting %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &APIError{Path: path, StatusCode: resp.StatusCode, Status: resp.Status, Message: string(msg)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error unmarshaling response of %s: %w", path, err)
+	}
+	return nil
+}
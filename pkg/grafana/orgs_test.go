@@ -0,0 +1,221 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckOrg(t *testing.T) {
+	for _, tc := range []struct {
+		orgs    []OrgOptions
+		orgID   int64
+		wantErr bool
+	}{
+		{orgID: 0},
+		// Without configured organizations, the token's organization is used.
+		{orgID: 5},
+		{orgs: []OrgOptions{{ID: 1}, {ID: 2}}, orgID: 2},
+		{orgs: []OrgOptions{{ID: 1}, {ID: 2}}, orgID: 0},
+		{orgs: []OrgOptions{{ID: 1}, {ID: 2}}, orgID: 3, wantErr: true},
+	} {
+		b := &Backend{orgs: tc.orgs}
+		err := b.checkOrg(tc.orgID)
+		if tc.wantErr != errors.Is(err, ErrUnknownOrg) {
+			t.Errorf("checkOrg(%d) with orgs %v: unexpected error %v", tc.orgID, tc.orgs, err)
+		}
+	}
+}
+
+func TestOrgCredentials(t *testing.T) {
+	b := &Backend{
+		authToken: "admin",
+		orgs:      []OrgOptions{{ID: 1}, {ID: 2, AuthToken: "team-b"}},
+	}
+	for _, tc := range []struct {
+		orgID      int64
+		wantToken  string
+		wantHeader string
+	}{
+		{orgID: 0, wantToken: "admin"},
+		{orgID: 1, wantToken: "admin", wantHeader: "1"},
+		{orgID: 2, wantToken: "team-b", wantHeader: "2"},
+	} {
+		if got := b.token(tc.orgID); got != tc.wantToken {
+			t.Errorf("token(%d) = %q, want %q", tc.orgID, got, tc.wantToken)
+		}
+		h := http.Header{}
+		// Clients can't select an organization themselves.
+		h.Set(orgIDHeader, "99")
+		b.setOrg(h, tc.orgID)
+		if got := h.Get(orgIDHeader); got != tc.wantHeader {
+			t.Errorf("setOrg(%d) set header %q, want %q", tc.orgID, got, tc.wantHeader)
+		}
+	}
+}
+
+func TestGetDatasourcesOfOrgs(t *testing.T) {
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org := r.Header.Get(orgIDHeader)
+		want := map[string]string{"1": "Bearer admin", "2": "Bearer team-b"}[org]
+		if got := r.Header.Get("Authorization"); got != want {
+			http.Error(w, fmt.Sprintf("unexpected credentials %q for organization %q", got, org), http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/org":
+			fmt.Fprintf(w, `{"id":%s,"name":"Org %s"}`, org, org)
+		case "/api/datasources":
+			fmt.Fprintf(w, `[{"id":%s1,"name":"prom","type":"prometheus"},{"id":%s2,"name":"loki","type":"loki"}]`, org, org)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer grafana.Close()
+
+	types, err := datasourceTypesMap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Backend{
+		url:       grafana.URL,
+		authToken: "admin",
+		types:     types,
+		orgs:      []OrgOptions{{ID: 1}, {ID: 2, AuthToken: "team-b"}},
+	}
+	ds, orgs, err := b.GetDatasources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orgs) != 2 || orgs[0] != (Org{ID: 1, Name: "Org 1"}) || orgs[1] != (Org{ID: 2, Name: "Org 2"}) {
+		t.Errorf("unexpected organizations %+v", orgs)
+	}
+	if len(ds) != 2 || ds[0].ID != 11 || ds[0].OrgID != 1 || ds[1].ID != 21 || ds[1].OrgID != 2 {
+		t.Errorf("unexpected datasources %+v", ds)
+	}
+
+	// A single failing organization fails the refresh, so that the cache keeps
+	// the last complete list.
+	b.orgs = append(b.orgs, OrgOptions{ID: 3})
+	if _, _, err := b.GetDatasources(); err == nil {
+		t.Error("expected error for inaccessible organization")
+	}
+}
+
+func TestDatasourceUIDInSeveralOrgs(t *testing.T) {
+	var proxied []string
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org := r.Header.Get(orgIDHeader)
+		switch r.URL.Path {
+		case "/api/org":
+			fmt.Fprintf(w, `{"id":%s,"name":"Org %s"}`, org, org)
+		case "/api/datasources":
+			// Both organizations have a datasource with the UID "prom".
+			fmt.Fprintf(w, `[{"id":%s1,"uid":"prom","name":"prom","type":"prometheus"},{"id":%s2,"uid":"prom-%s","name":"prom-%s","type":"prometheus"}]`, org, org, org, org)
+		default:
+			proxied = append(proxied, fmt.Sprintf("%s %s %s", r.URL.Path, org, r.Header.Get("Authorization")))
+			fmt.Fprint(w, `{"status":"success","data":{}}`)
+		}
+	}))
+	defer grafana.Close()
+
+	b, err := NewBackend(Options{
+		Logger:             slog.New(slog.DiscardHandler),
+		URL:                grafana.URL,
+		AuthToken:          "admin",
+		DatasourceCacheTTL: time.Hour,
+		Orgs:               []OrgOptions{{ID: 1}, {ID: 2, AuthToken: "team-b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if ds, _ := b.Datasources(); len(ds) == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("datasources were not fetched")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h := b.Handle("", nil)
+	for _, tc := range []struct {
+		path        string
+		status      int
+		wantProxied string
+	}{
+		{
+			path:   "/api/grafana/api/datasources/proxy/uid/prom/api/v1/query",
+			status: http.StatusBadRequest,
+		},
+		{
+			path:        "/api/grafana/api/datasources/proxy/uid/prom-2/api/v1/query",
+			status:      http.StatusOK,
+			wantProxied: "/api/datasources/proxy/uid/prom-2/api/v1/query 2 Bearer team-b",
+		},
+		{
+			path:        "/api/grafana/api/datasources/proxy/11/api/v1/query",
+			status:      http.StatusOK,
+			wantProxied: "/api/datasources/proxy/11/api/v1/query 1 Bearer admin",
+		},
+		{
+			path:   "/api/grafana/api/datasources/proxy/uid/prom-3/api/v1/query",
+			status: http.StatusNotFound,
+		},
+	} {
+		proxied = nil
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: got status %d, want %d: %s", tc.path, rec.Code, tc.status, rec.Body.String())
+			continue
+		}
+		if tc.wantProxied == "" {
+			if len(proxied) != 0 {
+				t.Errorf("%s: rejected request was proxied as %v", tc.path, proxied)
+			}
+			continue
+		}
+		if len(proxied) != 1 || proxied[0] != tc.wantProxied {
+			t.Errorf("%s: got proxied requests %q, want %q", tc.path, proxied, tc.wantProxied)
+		}
+	}
+
+	// Dashboards of a known organization resolve the UID in that organization,
+	// while ambiguous references without an organization stay unresolved.
+	for _, tc := range []struct {
+		orgID  int64
+		ref    string
+		wantID int64
+	}{
+		{orgID: 1, ref: "prom", wantID: 11},
+		{orgID: 2, ref: "prom", wantID: 21},
+		{orgID: 0, ref: "prom"},
+		{orgID: 0, ref: "prom-2", wantID: 22},
+		{orgID: 1, ref: "prom-2"},
+	} {
+		if got := b.resolveDashboardDatasource(tc.orgID, tc.ref, nil); got.ID != tc.wantID {
+			t.Errorf("resolveDashboardDatasource(%d, %q): got datasource ID %d, want %d", tc.orgID, tc.ref, got.ID, tc.wantID)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/promlens/pkg/auth"
+)
+
+// Modes for authenticating datasource requests against Grafana.
+const (
+	// UserAuthToken sends all datasource requests with the API token.
+	UserAuthToken = "token"
+	// UserAuthSession forwards the user's Grafana session cookie.
+	UserAuthSession = "session"
+	// UserAuthProxy sends the name of the user that is logged into PromLens
+	// in a header, for Grafana's auth proxy authentication.
+	UserAuthProxy = "auth_proxy"
+)
+
+// ErrNoUserCredentials is returned when a datasource request can't be sent as
+// the requesting user, because the user's credentials are missing.
+var ErrNoUserCredentials = errors.New("no Grafana credentials for the current user")
+
+// UserAuthConfig configures as which user datasource requests are sent to
+// Grafana, so that Grafana's datasource permissions and audit logs apply to
+// each user. The API token is still used to list the datasources.
+type UserAuthConfig struct {
+	Mode string `yaml:"mode"`
+	// SessionCookie is the name of Grafana's session cookie in session mode.
+	SessionCookie string `yaml:"session_cookie,omitempty"`
+	// AuthProxyHeader and AuthProxyEmailHeader are the headers that Grafana's
+	// auth proxy reads the user's name and email address from.
+	AuthProxyHeader      string `yaml:"auth_proxy_header,omitempty"`
+	AuthProxyEmailHeader string `yaml:"auth_proxy_email_header,omitempty"`
+}
+
+// DefaultUserAuthConfig is the default user authentication configuration.
+var DefaultUserAuthConfig = UserAuthConfig{
+	Mode:            UserAuthToken,
+	SessionCookie:   "grafana_session",
+	AuthProxyHeader: "X-WEBAUTH-USER",
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *UserAuthConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultUserAuthConfig
+	type plain UserAuthConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	switch c.Mode {
+	case UserAuthToken, UserAuthSession, UserAuthProxy:
+	default:
+		return fmt.Errorf("invalid Grafana user authentication mode %q, must be one of %q, %q, or %q", c.Mode, UserAuthToken, UserAuthSession, UserAuthProxy)
+	}
+	if c.SessionCookie == "" || c.AuthProxyHeader == "" {
+		return errors.New("session_cookie and auth_proxy_header must not be empty")
+	}
+	return nil
+}
+
+// checkCredentials returns ErrNoUserCredentials if the request doesn't carry
+// the credentials that are needed to send datasource requests as its user.
+func (b *Backend) checkCredentials(r *http.Request) error {
+	switch b.userAuth.Mode {
+	case UserAuthSession:
+		if _, err := r.Cookie(b.userAuth.SessionCookie); err != nil {
+			return fmt.Errorf("%w: missing Grafana session, please log in to Grafana", ErrNoUserCredentials)
+		}
+	case UserAuthProxy:
+		if _, ok := auth.FromContext(r.Context()); !ok {
+			return fmt.Errorf("%w: PromLens authentication is not enabled", ErrNoUserCredentials)
+		}
+	}
+	return nil
+}
+
+// setCredentials sets the credentials of the outgoing request to Grafana,
+// based on the user of the incoming request, for the organization with the
+// given ID. Both requests may be the same.
+func (b *Backend) setCredentials(out, in *http.Request, orgID int64) {
+	sessionCookie, _ := in.Cookie(b.userAuth.SessionCookie)
+	id, _ := auth.FromContext(in.Context())
+
+	// Never pass on credentials chosen by the client, and don't leak the
+	// user's PromLens session or other cookies to Grafana.
+	out.Header.Del("Authorization")
+	out.Header.Del("Cookie")
+	out.Header.Del(b.userAuth.AuthProxyHeader)
+	if b.userAuth.AuthProxyEmailHeader != "" {
+		out.Header.Del(b.userAuth.AuthProxyEmailHeader)
+	}
+
+	b.setOrg(out.Header, orgID)
+
+	switch b.userAuth.Mode {
+	case UserAuthSession:
+		if sessionCookie != nil {
+			out.AddCookie(&http.Cookie{Name: sessionCookie.Name, Value: sessionCookie.Value})
+		}
+	case UserAuthProxy:
+		if id != nil {
+			out.Header.Set(b.userAuth.AuthProxyHeader, id.User)
+			if b.userAuth.AuthProxyEmailHeader != "" && id.Email != "" {
+				out.Header.Set(b.userAuth.AuthProxyEmailHeader, id.Email)
+			}
+		}
+	default:
+		out.Header.Set("Authorization", "Bearer "+b.token(orgID))
+	}
+}
+
+// userRoundTripper sends requests to a datasource with the credentials of the
+// user of an incoming request.
+type userRoundTripper struct {
+	b    *Backend
+	in   *http.Request
+	ds   DatasourceSettings
+	next http.RoundTripper
+}
+
+func (rt *userRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	rt.b.setCredentials(req, rt.in, rt.ds.OrgID)
+	rt.b.setDatasourceHeaders(req.Header, rt.ds)
+	return rt.next.RoundTrip(req)
+}
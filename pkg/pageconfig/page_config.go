@@ -19,15 +19,65 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/prometheus/promlens/pkg/audit"
 	"github.com/prometheus/promlens/pkg/grafana"
 	"github.com/prometheus/promlens/pkg/sharer"
 )
 
+// PrometheusServer is a named Prometheus server that users can select in the UI.
+type PrometheusServer struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	IsDefault   bool   `json:"isDefault"`
+}
+
+// GrafanaDatasource is the part of a Grafana datasource's settings that the UI
+// needs. Unlike grafana.DatasourceSettings, it doesn't contain any secrets.
+type GrafanaDatasource struct {
+	ID        int64  `json:"id"`
+	OrgID     int64  `json:"orgID"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Access    string `json:"access"`
+	IsDefault bool   `json:"isDefault"`
+	// WithCredentials, BasicAuth, and CustomHeaders are only relevant for
+	// datasources with direct browser access.
+	WithCredentials bool `json:"withCredentials"`
+	BasicAuth       bool `json:"basicAuth"`
+	CustomHeaders   bool `json:"customHeaders"`
+	// Health is the result of the last health check, or nil if the datasource
+	// hasn't been checked.
+	Health *grafana.DatasourceHealth `json:"health"`
+}
+
+func newGrafanaDatasource(ds grafana.DatasourceSettings) GrafanaDatasource {
+	_, customHeaders := ds.JSONData["httpHeaderName1"]
+	return GrafanaDatasource{
+		ID:              ds.ID,
+		OrgID:           ds.OrgID,
+		Name:            ds.Name,
+		URL:             ds.URL,
+		Access:          ds.Access,
+		IsDefault:       ds.IsDefault,
+		WithCredentials: ds.WithCredentials,
+		BasicAuth:       ds.BasicAuth,
+		CustomHeaders:   customHeaders,
+	}
+}
+
 type pageConfig struct {
-	Now                  int64                        `json:"now"`
-	GrafanaDatasources   []grafana.DatasourceSettings `json:"grafanaDatasources"`
-	PageState            map[string]interface{}       `json:"pageState"`
-	DefaultPrometheusURL string                       `json:"defaultPrometheusURL"`
+	Now                int64               `json:"now"`
+	GrafanaDatasources []GrafanaDatasource `json:"grafanaDatasources"`
+	// GrafanaDatasourceStatus is nil if no Grafana backend is configured.
+	GrafanaDatasourceStatus *grafana.DatasourceStatus `json:"grafanaDatasourceStatus"`
+	// GrafanaOrgs are the organizations that the datasources belong to, or
+	// empty if Grafana organizations aren't configured.
+	GrafanaOrgs          []grafana.Org          `json:"grafanaOrgs"`
+	PrometheusServers    []PrometheusServer     `json:"prometheusServers"`
+	PageState            map[string]interface{} `json:"pageState"`
+	DefaultPrometheusURL string                 `json:"defaultPrometheusURL"`
+	ParserFeatures       []string               `json:"parserFeatures"`
 }
 
 func Handle(
@@ -35,26 +85,38 @@ func Handle(
 	gb *grafana.Backend,
 	defaultPrometheusURL string,
 	defaultGrafanaDatasourceID int64,
+	prometheusServers []PrometheusServer,
+	parserFeatures []string,
+	al *audit.Logger,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ds := []grafana.DatasourceSettings{}
-		var err error
+		ds := []GrafanaDatasource{}
+		orgs := []grafana.Org{}
+		var dsStatus *grafana.DatasourceStatus
 		if gb != nil {
-			ds, err = gb.GetDatasources()
-			if err != nil {
-				w.WriteHeader(http.StatusBadGateway)
-				fmt.Fprintf(w, "Error fetching datasources from Grafana: %v", err)
-				return
+			// Even if Grafana is unavailable, the page still loads, so that users
+			// can query Prometheus directly.
+			settings, status := gb.Datasources()
+			dsStatus = &status
+			if o := gb.Orgs(); o != nil {
+				orgs = o
 			}
 
-			if defaultGrafanaDatasourceID != 0 {
-				for i := range ds {
-					if ds[i].ID == defaultGrafanaDatasourceID {
-						ds[i].IsDefault = true
-					} else {
-						ds[i].IsDefault = false
-					}
+			// Every organization has its own default datasource, but only one
+			// of them can be the default in PromLens.
+			hasDefault := false
+			for _, s := range settings {
+				d := newGrafanaDatasource(s)
+				if h, ok := gb.Health(s.ID); ok {
+					d.Health = &h
+				}
+				if defaultGrafanaDatasourceID != 0 {
+					d.IsDefault = d.ID == defaultGrafanaDatasourceID
+				} else if hasDefault {
+					d.IsDefault = false
 				}
+				hasDefault = hasDefault || d.IsDefault
+				ds = append(ds, d)
 			}
 		}
 
@@ -78,15 +140,20 @@ func Handle(
 				fmt.Fprintf(w, "Error unmarshaling shared page state from JSON: %v", err)
 				return
 			}
+			al.Log(r, audit.Event{Action: audit.ActionLoadLink, Link: name})
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		// TODO do something with this error
 		_ = json.NewEncoder(w).Encode(pageConfig{
-			Now:                  time.Now().Unix(),
-			GrafanaDatasources:   ds,
-			PageState:            pageState,
-			DefaultPrometheusURL: defaultPrometheusURL,
+			Now:                     time.Now().Unix(),
+			GrafanaDatasources:      ds,
+			GrafanaDatasourceStatus: dsStatus,
+			GrafanaOrgs:             orgs,
+			PrometheusServers:       prometheusServers,
+			PageState:               pageState,
+			DefaultPrometheusURL:    defaultPrometheusURL,
+			ParserFeatures:          parserFeatures,
 		})
 	}
 }
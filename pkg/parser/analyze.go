@@ -0,0 +1,317 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grafana/regexp/syntax"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	prom_httputil "github.com/prometheus/prometheus/util/httputil"
+)
+
+const (
+	// Defaults for the analysis parameters when they are not provided in the request.
+	defaultScrapeInterval = 15 * time.Second
+	defaultSubqueryStep   = time.Minute
+
+	// Thresholds above which the analysis emits warnings.
+	maxSubqueryPoints = 10000
+	maxEvaluations    = 100000
+	maxRangeWindow    = 7 * 24 * time.Hour
+)
+
+// analysisParams describes the context in which a query is going to be run.
+type analysisParams struct {
+	// Range and Step of the outer range query. A zero Range means an instant query.
+	Range time.Duration
+	Step  time.Duration
+	// ScrapeInterval is the assumed interval between samples of each series.
+	ScrapeInterval time.Duration
+	// DefaultSubqueryStep is used for subqueries that don't specify a step.
+	DefaultSubqueryStep time.Duration
+}
+
+// selectorCost describes the estimated work of a single selector.
+type selectorCost struct {
+	Path        string  `json:"path"`
+	Selector    string  `json:"selector"`
+	Evaluations int64   `json:"evaluations"`
+	WindowMs    int64   `json:"windowMs"`
+	Cost        float64 `json:"cost"`
+}
+
+type analysisWarning struct {
+	Path    string `json:"path"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// analysis is the result of estimating the cost of a query.
+type analysis struct {
+	// Score is the estimated total number of samples per series that the query reads.
+	Score                   float64           `json:"score"`
+	Selectors               int               `json:"selectors"`
+	UnboundedSelectors      int               `json:"unboundedSelectors"`
+	Subqueries              int               `json:"subqueries"`
+	TotalRangeMs            int64             `json:"totalRangeMs"`
+	TotalSubqueryRangeMs    int64             `json:"totalSubqueryRangeMs"`
+	OuterEvaluations        int64             `json:"outerEvaluations"`
+	MaxEvaluations          int64             `json:"maxEvaluations"`
+	AssumedScrapeIntervalMs int64             `json:"assumedScrapeIntervalMs"`
+	AssumedSubqueryStepMs   int64             `json:"assumedSubqueryStepMs"`
+	Breakdown               []selectorCost    `json:"breakdown"`
+	Warnings                []analysisWarning `json:"warnings"`
+}
+
+// analyze estimates the work that a query will do. Each selector's cost is the
+// number of samples per series that it reads in total: one sample per
+// evaluation for instant vector selectors and the number of samples in the
+// range window per evaluation for range vector selectors. Subqueries multiply
+// the number of evaluations of everything below them by their number of steps.
+func analyze(expr parser.Expr, p analysisParams) *analysis {
+	outerEvals := int64(1)
+	if p.Range > 0 && p.Step > 0 {
+		outerEvals = saturatingInt(float64(p.Range/p.Step) + 1)
+	}
+
+	a := &analysis{
+		OuterEvaluations:        outerEvals,
+		MaxEvaluations:          outerEvals,
+		AssumedScrapeIntervalMs: p.ScrapeInterval.Milliseconds(),
+		AssumedSubqueryStepMs:   p.DefaultSubqueryStep.Milliseconds(),
+		Breakdown:               []selectorCost{},
+		Warnings:                []analysisWarning{},
+	}
+	a.analyzeNode(expr, "", outerEvals, p)
+	return a
+}
+
+// saturatingInt converts a non-negative float to an int64, saturating at
+// math.MaxInt64 instead of overflowing.
+func saturatingInt(f float64) int64 {
+	if f >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(f)
+}
+
+// saturatingMul multiplies two non-negative numbers, saturating at
+// math.MaxInt64 instead of overflowing.
+func saturatingMul(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+func (a *analysis) warn(path, kind, format string, args ...interface{}) {
+	a.Warnings = append(a.Warnings, analysisWarning{Path: path, Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+func (a *analysis) analyzeNode(node parser.Expr, path string, evals int64, p analysisParams) {
+	switch n := node.(type) {
+	case *parser.AggregateExpr:
+		if n.Param != nil {
+			a.analyzeNode(n.Param, childPath(path, "param"), evals, p)
+		}
+		a.analyzeNode(n.Expr, childPath(path, "expr"), evals, p)
+	case *parser.BinaryExpr:
+		a.analyzeNode(n.LHS, childPath(path, "lhs"), evals, p)
+		a.analyzeNode(n.RHS, childPath(path, "rhs"), evals, p)
+	case *parser.Call:
+		for i, arg := range n.Args {
+			a.analyzeNode(arg, argPath(path, i), evals, p)
+		}
+	case *parser.MatrixSelector:
+		vs := n.VectorSelector.(*parser.VectorSelector)
+		a.TotalRangeMs += n.Range.Milliseconds()
+		samples := math.Max(1, math.Ceil(float64(n.Range)/float64(p.ScrapeInterval)))
+		a.addSelector(path, n.String(), vs, evals, n.Range, samples)
+		if n.Range > maxRangeWindow {
+			a.warn(path, "longRange", "Range window of %s exceeds %s", model.Duration(n.Range), model.Duration(maxRangeWindow))
+		}
+	case *parser.SubqueryExpr:
+		a.Subqueries++
+		a.TotalSubqueryRangeMs += n.Range.Milliseconds()
+		step := n.Step
+		if step == 0 {
+			step = p.DefaultSubqueryStep
+		}
+		points := saturatingInt(math.Ceil(float64(n.Range) / float64(step)))
+		if points > maxSubqueryPoints {
+			a.warn(path, "subqueryResolution", "Subquery evaluates its inner expression %d times per outer evaluation (range %s at step %s)", points, model.Duration(n.Range), model.Duration(step))
+		}
+		if n.Range > maxRangeWindow {
+			a.warn(path, "longRange", "Subquery range of %s exceeds %s", model.Duration(n.Range), model.Duration(maxRangeWindow))
+		}
+		a.analyzeNode(n.Expr, childPath(path, "expr"), saturatingMul(evals, points), p)
+	case *parser.ParenExpr:
+		a.analyzeNode(n.Expr, childPath(path, "expr"), evals, p)
+	case *parser.UnaryExpr:
+		a.analyzeNode(n.Expr, childPath(path, "expr"), evals, p)
+	case *parser.VectorSelector:
+		a.addSelector(path, n.String(), n, evals, 0, 1)
+	}
+}
+
+func (a *analysis) addSelector(path, selector string, vs *parser.VectorSelector, evals int64, window time.Duration, samplesPerEval float64) {
+	cost := float64(evals) * samplesPerEval
+	a.Selectors++
+	a.Score += cost
+	a.MaxEvaluations = max(a.MaxEvaluations, evals)
+	a.Breakdown = append(a.Breakdown, selectorCost{
+		Path:        path,
+		Selector:    selector,
+		Evaluations: evals,
+		WindowMs:    window.Milliseconds(),
+		Cost:        cost,
+	})
+
+	if evals > maxEvaluations {
+		a.warn(path, "manyEvaluations", "Selector is evaluated %d times", evals)
+	}
+
+	bounded := false
+	for _, m := range vs.LabelMatchers {
+		if isUnboundedRegex(m) {
+			a.warn(path, "unboundedRegex", "Matcher %s matches (almost) any value and is expensive to evaluate", m)
+		}
+		if !m.Matches("") && (m.Type == labels.MatchEqual || m.Type == labels.MatchRegexp) && !isUnboundedRegex(m) {
+			bounded = true
+		}
+	}
+	if !bounded {
+		a.UnboundedSelectors++
+		a.warn(path, "unboundedSelector", "Selector %s has no matcher that restricts it to a specific metric or label value and may select a large number of series", selector)
+	}
+}
+
+// isUnboundedRegex returns whether a matcher is a regex matcher that can start
+// with a wildcard (like ".*foo", "(.+)bar", or ".*|foo"), and thus matches a
+// large part of all label values and can't make use of index lookups by prefix.
+func isUnboundedRegex(m *labels.Matcher) bool {
+	if m.Type != labels.MatchRegexp && m.Type != labels.MatchNotRegexp {
+		return false
+	}
+	re, err := syntax.Parse(m.Value, syntax.Perl|syntax.DotNL)
+	if err != nil {
+		return false
+	}
+	return startsWithWildcard(re.Simplify())
+}
+
+// startsWithWildcard returns whether a regex can start with an unbounded
+// repetition of any character.
+func startsWithWildcard(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		return re.Sub[0].Op == syntax.OpAnyChar || re.Sub[0].Op == syntax.OpAnyCharNotNL
+	case syntax.OpCapture:
+		return startsWithWildcard(re.Sub[0])
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if startsWithWildcard(sub) {
+				return true
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			switch sub.Op {
+			case syntax.OpBeginLine, syntax.OpBeginText, syntax.OpEmptyMatch:
+				continue
+			}
+			return startsWithWildcard(sub)
+		}
+	}
+	return false
+}
+
+// parseDurationParam parses a duration that is either given in Prometheus
+// duration format or as a (floating point) number of seconds.
+func parseDurationParam(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		if secs < 0 || math.IsNaN(secs) || math.IsInf(secs, 0) {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		// Larger values would overflow time.Duration.
+		if secs >= math.MaxInt64/float64(time.Second) {
+			return 0, fmt.Errorf("invalid duration %q: too large", s)
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := model.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	return time.Duration(d), nil
+}
+
+// HandleAnalyze estimates the cost of the expression in the "expr" parameter.
+// The optional "range" and "step" parameters describe the outer range query
+// (an instant query is assumed if they are absent), "scrape_interval" the
+// assumed interval between samples, and "subquery_step" the step used for
+// subqueries without an explicit step.
+func HandleAnalyze(features Features) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prom_httputil.SetCORS(w, CORSOrigin, r)
+		input := r.FormValue("expr")
+		reqFeatures, err := ParseFeatures(r.Form["enable_feature"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var p analysisParams
+		for _, d := range []struct {
+			param string
+			dst   *time.Duration
+			def   time.Duration
+		}{
+			{"range", &p.Range, 0},
+			{"step", &p.Step, 0},
+			{"scrape_interval", &p.ScrapeInterval, defaultScrapeInterval},
+			{"subquery_step", &p.DefaultSubqueryStep, defaultSubqueryStep},
+		} {
+			if *d.dst, err = parseDurationParam(r.FormValue(d.param), d.def); err != nil {
+				http.Error(w, fmt.Sprintf("Error parsing %q parameter: %v", d.param, err), http.StatusBadRequest)
+				return
+			}
+		}
+		if p.Range > 0 && p.Step == 0 {
+			http.Error(w, `The "step" parameter is required when "range" is set`, http.StatusBadRequest)
+			return
+		}
+		if p.ScrapeInterval == 0 || p.DefaultSubqueryStep == 0 {
+			http.Error(w, `The "scrape_interval" and "subquery_step" parameters must be positive`, http.StatusBadRequest)
+			return
+		}
+
+		expr, err := ParseExpr(input, features.Merge(reqFeatures))
+		if err != nil {
+			writeError(w, parseError(err))
+			return
+		}
+		writeJSON(w, analyze(expr, p))
+	}
+}
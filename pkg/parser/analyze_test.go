@@ -0,0 +1,171 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func TestAnalyze(t *testing.T) {
+	defaults := analysisParams{ScrapeInterval: 15 * time.Second, DefaultSubqueryStep: time.Minute}
+	rangeQuery := defaults
+	rangeQuery.Range, rangeQuery.Step = time.Hour, time.Minute
+
+	for _, tc := range []struct {
+		expr           string
+		params         analysisParams
+		score          float64
+		maxEvaluations int64
+		warnings       []string
+	}{
+		{expr: `up{job="api"}`, params: defaults, score: 1, maxEvaluations: 1},
+		{expr: `rate(x[5m])`, params: defaults, score: 20, maxEvaluations: 1},
+		{expr: `rate(x[5m])`, params: rangeQuery, score: 61 * 20, maxEvaluations: 61},
+		{expr: `x + y`, params: rangeQuery, score: 122, maxEvaluations: 61},
+		{expr: `max_over_time(x[1h:])`, params: defaults, score: 60, maxEvaluations: 60},
+		{expr: `max_over_time(rate(x[1m])[1h:5m])`, params: defaults, score: 12 * 4, maxEvaluations: 12},
+		{expr: `rate(x[30d])`, params: defaults, score: 172800, maxEvaluations: 1, warnings: []string{"longRange"}},
+		{expr: `max_over_time(x[1d:1s])`, params: defaults, score: 86400, maxEvaluations: 86400, warnings: []string{"subqueryResolution"}},
+		{expr: `{job=~".+"}`, params: defaults, score: 1, maxEvaluations: 1, warnings: []string{"unboundedRegex", "unboundedSelector"}},
+		{expr: `x{job=~"(.*)-api"}`, params: defaults, score: 1, maxEvaluations: 1, warnings: []string{"unboundedRegex"}},
+		{expr: `1 + 2`, params: defaults, maxEvaluations: 1},
+		// Nested subqueries with tiny steps must not overflow.
+		{
+			expr:           `max_over_time(max_over_time(max_over_time(x[10000d:1ms])[10000d:1ms])[10000d:1ms])`,
+			params:         defaults,
+			score:          math.MaxInt64,
+			maxEvaluations: math.MaxInt64,
+			warnings:       []string{"subqueryResolution", "longRange", "subqueryResolution", "longRange", "subqueryResolution", "longRange", "manyEvaluations"},
+		},
+		{
+			expr:           `x`,
+			params:         analysisParams{Range: math.MaxInt64, Step: 1, ScrapeInterval: time.Second, DefaultSubqueryStep: time.Minute},
+			score:          math.MaxInt64,
+			maxEvaluations: math.MaxInt64,
+			warnings:       []string{"manyEvaluations"},
+		},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tc.expr, Features{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := analyze(expr, tc.params)
+			if a.Score != tc.score {
+				t.Errorf("got score %g, want %g", a.Score, tc.score)
+			}
+			if a.MaxEvaluations != tc.maxEvaluations {
+				t.Errorf("got %d max evaluations, want %d", a.MaxEvaluations, tc.maxEvaluations)
+			}
+			var kinds []string
+			for _, w := range a.Warnings {
+				kinds = append(kinds, w.Kind)
+			}
+			if !slices.Equal(kinds, tc.warnings) {
+				t.Errorf("got warnings %v, want %v", kinds, tc.warnings)
+			}
+		})
+	}
+}
+
+func TestIsUnboundedRegex(t *testing.T) {
+	for _, tc := range []struct {
+		typ   labels.MatchType
+		value string
+		want  bool
+	}{
+		{labels.MatchRegexp, ".*", true},
+		{labels.MatchRegexp, ".+foo", true},
+		{labels.MatchRegexp, "(.*)", true},
+		{labels.MatchRegexp, "(?:.+)bar", true},
+		{labels.MatchRegexp, ".*|foo", true},
+		{labels.MatchRegexp, "foo|.*bar", true},
+		{labels.MatchRegexp, "^.*", true},
+		{labels.MatchNotRegexp, ".*foo", true},
+		{labels.MatchRegexp, "foo.*", false},
+		{labels.MatchRegexp, "foo|bar", false},
+		{labels.MatchRegexp, ".?foo", false},
+		{labels.MatchRegexp, "[a-z].*", false},
+		{labels.MatchEqual, ".*", false},
+	} {
+		m := labels.MustNewMatcher(tc.typ, "job", tc.value)
+		if got := isUnboundedRegex(m); got != tc.want {
+			t.Errorf("isUnboundedRegex(%s) = %t, want %t", m, got, tc.want)
+		}
+	}
+}
+
+func TestParseDurationParam(t *testing.T) {
+	for _, tc := range []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "", want: time.Minute},
+		{s: "90", want: 90 * time.Second},
+		{s: "0.5", want: 500 * time.Millisecond},
+		{s: "5m", want: 5 * time.Minute},
+		{s: "9223372036", want: 9223372036 * time.Second},
+		{s: "9223372037", wantErr: true},
+		{s: "1e12", wantErr: true},
+		{s: "-1", wantErr: true},
+		{s: "-5m", wantErr: true},
+		{s: "NaN", wantErr: true},
+		{s: "+Inf", wantErr: true},
+		{s: "1000000y", wantErr: true},
+		{s: "foo", wantErr: true},
+	} {
+		got, err := parseDurationParam(tc.s, time.Minute)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseDurationParam(%q): expected error, got %s", tc.s, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseDurationParam(%q) = %s, %v, want %s", tc.s, got, err, tc.want)
+		}
+	}
+}
+
+func TestHandleAnalyze(t *testing.T) {
+	for _, tc := range []struct {
+		params url.Values
+		status int
+		want   string
+	}{
+		{params: url.Values{"expr": {"rate(x[5m])"}}, status: http.StatusOK, want: `"score":20`},
+		{params: url.Values{"expr": {"rate(x[5m])"}, "range": {"3600"}, "step": {"1m"}}, status: http.StatusOK, want: `"outerEvaluations":61`},
+		{params: url.Values{"expr": {"rate(x[5m])"}, "range": {"1h"}}, status: http.StatusBadRequest, want: `"step" parameter is required`},
+		{params: url.Values{"expr": {"x"}, "scrape_interval": {"-1"}}, status: http.StatusBadRequest, want: "invalid duration"},
+		{params: url.Values{"expr": {"x"}, "range": {"1e12"}, "step": {"1m"}}, status: http.StatusBadRequest, want: "too large"},
+		{params: url.Values{"expr": {"x"}, "range": {"1h"}, "step": {"-60"}}, status: http.StatusBadRequest, want: "invalid duration"},
+		{params: url.Values{"expr": {"x("}}, status: http.StatusBadRequest, want: `"type":"error"`},
+	} {
+		rec := httptest.NewRecorder()
+		HandleAnalyze(Features{})(rec, httptest.NewRequest(http.MethodGet, "/api/analyze?"+tc.params.Encode(), nil))
+		if rec.Code != tc.status || !strings.Contains(rec.Body.String(), tc.want) {
+			t.Errorf("%v: got status %d and body %q, want status %d and body containing %q", tc.params, rec.Code, rec.Body.String(), tc.status, tc.want)
+		}
+	}
+}
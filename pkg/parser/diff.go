@@ -0,0 +1,328 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	prom_httputil "github.com/prometheus/prometheus/util/httputil"
+)
+
+// Kinds of changes between two ASTs.
+const (
+	changeAdded      = "added"
+	changeRemoved    = "removed"
+	changeReplaced   = "replaced"
+	changeOperator   = "operatorChanged"
+	changeFunction   = "functionChanged"
+	changeGrouping   = "groupingChanged"
+	changeMatching   = "matchingChanged"
+	changeMetricName = "metricNameChanged"
+	changeMatchers   = "matchersChanged"
+	changeRange      = "rangeChanged"
+	changeStep       = "stepChanged"
+	changeOffset     = "offsetChanged"
+	changeAt         = "atModifierChanged"
+	changeValue      = "valueChanged"
+)
+
+// change describes a single difference between two ASTs. The path refers to
+// the location of the changed node (in the new AST for added nodes, in the old
+// AST otherwise), using the field names of the JSON AST format (for example
+// "lhs.args[0].expr"). For matcher changes, Old and New contain the removed and
+// added matchers.
+type change struct {
+	Path string      `json:"path"`
+	Kind string      `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+type groupingChange struct {
+	Grouping []string `json:"grouping"`
+	Without  bool     `json:"without"`
+}
+
+type matchingChange struct {
+	Card    string   `json:"card"`
+	Labels  []string `json:"labels"`
+	On      bool     `json:"on"`
+	Include []string `json:"include"`
+	Bool    bool     `json:"bool"`
+}
+
+func childPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func argPath(path string, i int) string {
+	return childPath(path, fmt.Sprintf("args[%d]", i))
+}
+
+// diffAST returns the structural differences between two ASTs.
+func diffAST(a, b parser.Expr) []change {
+	changes := []change{}
+	diffNode(a, b, "", &changes)
+	return changes
+}
+
+func diffNode(a, b parser.Expr, path string, changes *[]change) {
+	add := func(kind string, oldVal, newVal interface{}) {
+		*changes = append(*changes, change{Path: path, Kind: kind, Old: oldVal, New: newVal})
+	}
+
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		add(changeAdded, nil, b.String())
+		return
+	case b == nil:
+		add(changeRemoved, a.String(), nil)
+		return
+	}
+
+	if !sameNodeType(a, b) {
+		// Detect whether a node was wrapped around or removed from around an
+		// otherwise unchanged subtree, e.g. "foo" -> "sum(foo)".
+		for _, c := range parser.Children(b) {
+			if e, ok := c.(parser.Expr); ok && e.String() == a.String() {
+				add(changeAdded, nil, b.String())
+				return
+			}
+		}
+		for _, c := range parser.Children(a) {
+			if e, ok := c.(parser.Expr); ok && e.String() == b.String() {
+				add(changeRemoved, a.String(), nil)
+				return
+			}
+		}
+		add(changeReplaced, a.String(), b.String())
+		return
+	}
+
+	switch na := a.(type) {
+	case *parser.AggregateExpr:
+		nb := b.(*parser.AggregateExpr)
+		if na.Op != nb.Op {
+			add(changeOperator, na.Op.String(), nb.Op.String())
+		}
+		if na.Without != nb.Without || !sameLabelSet(na.Grouping, nb.Grouping) {
+			add(changeGrouping,
+				groupingChange{Grouping: sanitizeList(na.Grouping), Without: na.Without},
+				groupingChange{Grouping: sanitizeList(nb.Grouping), Without: nb.Without},
+			)
+		}
+		diffNode(na.Param, nb.Param, childPath(path, "param"), changes)
+		diffNode(na.Expr, nb.Expr, childPath(path, "expr"), changes)
+	case *parser.BinaryExpr:
+		nb := b.(*parser.BinaryExpr)
+		if na.Op != nb.Op {
+			add(changeOperator, na.Op.String(), nb.Op.String())
+		}
+		if ma, mb := translateMatching(na), translateMatching(nb); !sameMatching(ma, mb) {
+			add(changeMatching, optionalMatching(ma), optionalMatching(mb))
+		}
+		diffNode(na.LHS, nb.LHS, childPath(path, "lhs"), changes)
+		diffNode(na.RHS, nb.RHS, childPath(path, "rhs"), changes)
+	case *parser.Call:
+		nb := b.(*parser.Call)
+		if na.Func.Name != nb.Func.Name {
+			add(changeFunction, na.Func.Name, nb.Func.Name)
+		}
+		for i := 0; i < max(len(na.Args), len(nb.Args)); i++ {
+			var argA, argB parser.Expr
+			if i < len(na.Args) {
+				argA = na.Args[i]
+			}
+			if i < len(nb.Args) {
+				argB = nb.Args[i]
+			}
+			diffNode(argA, argB, argPath(path, i), changes)
+		}
+	case *parser.MatrixSelector:
+		nb := b.(*parser.MatrixSelector)
+		diffSelector(na.VectorSelector.(*parser.VectorSelector), nb.VectorSelector.(*parser.VectorSelector), add)
+		if na.Range != nb.Range {
+			add(changeRange, na.Range.Milliseconds(), nb.Range.Milliseconds())
+		}
+	case *parser.SubqueryExpr:
+		nb := b.(*parser.SubqueryExpr)
+		if na.Range != nb.Range {
+			add(changeRange, na.Range.Milliseconds(), nb.Range.Milliseconds())
+		}
+		if na.Step != nb.Step {
+			add(changeStep, na.Step.Milliseconds(), nb.Step.Milliseconds())
+		}
+		if na.OriginalOffset != nb.OriginalOffset {
+			add(changeOffset, na.OriginalOffset.Milliseconds(), nb.OriginalOffset.Milliseconds())
+		}
+		diffAt(na.Timestamp, nb.Timestamp, na.StartOrEnd, nb.StartOrEnd, add)
+		diffNode(na.Expr, nb.Expr, childPath(path, "expr"), changes)
+	case *parser.NumberLiteral:
+		nb := b.(*parser.NumberLiteral)
+		if na.String() != nb.String() {
+			add(changeValue, na.String(), nb.String())
+		}
+	case *parser.StringLiteral:
+		nb := b.(*parser.StringLiteral)
+		if na.Val != nb.Val {
+			add(changeValue, na.Val, nb.Val)
+		}
+	case *parser.ParenExpr:
+		diffNode(na.Expr, b.(*parser.ParenExpr).Expr, childPath(path, "expr"), changes)
+	case *parser.UnaryExpr:
+		nb := b.(*parser.UnaryExpr)
+		if na.Op != nb.Op {
+			add(changeOperator, na.Op.String(), nb.Op.String())
+		}
+		diffNode(na.Expr, nb.Expr, childPath(path, "expr"), changes)
+	case *parser.VectorSelector:
+		diffSelector(na, b.(*parser.VectorSelector), add)
+	}
+}
+
+func sameNodeType(a, b parser.Expr) bool {
+	return fmt.Sprintf("%T", a) == fmt.Sprintf("%T", b)
+}
+
+func sameLabelSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func translateMatching(n *parser.BinaryExpr) *matchingChange {
+	m := n.VectorMatching
+	if m == nil {
+		if !n.ReturnBool {
+			return nil
+		}
+		return &matchingChange{Bool: true, Labels: []string{}, Include: []string{}}
+	}
+	return &matchingChange{
+		Card:    m.Card.String(),
+		Labels:  sanitizeList(m.MatchingLabels),
+		On:      m.On,
+		Include: sanitizeList(m.Include),
+		Bool:    n.ReturnBool,
+	}
+}
+
+// optionalMatching returns a nil interface for missing vector matching, so
+// that it is omitted from a change.
+func optionalMatching(m *matchingChange) interface{} {
+	if m == nil {
+		return nil
+	}
+	return m
+}
+
+func sameMatching(a, b *matchingChange) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Card == b.Card && a.On == b.On && a.Bool == b.Bool &&
+		sameLabelSet(a.Labels, b.Labels) && sameLabelSet(a.Include, b.Include)
+}
+
+func diffSelector(a, b *parser.VectorSelector, add func(kind string, oldVal, newVal interface{})) {
+	if a.Name != b.Name {
+		add(changeMetricName, a.Name, b.Name)
+	}
+
+	matcherStrings := func(vs *parser.VectorSelector) []string {
+		out := make([]string, 0, len(vs.LabelMatchers))
+		for _, m := range vs.LabelMatchers {
+			// The metric name matcher is already covered by the name comparison above.
+			if m.Name == labels.MetricName && m.Type == labels.MatchEqual && m.Value == vs.Name {
+				continue
+			}
+			out = append(out, m.String())
+		}
+		return out
+	}
+	ma, mb := matcherStrings(a), matcherStrings(b)
+	removed, added := []string{}, []string{}
+	for _, m := range ma {
+		if !slices.Contains(mb, m) {
+			removed = append(removed, m)
+		}
+	}
+	for _, m := range mb {
+		if !slices.Contains(ma, m) {
+			added = append(added, m)
+		}
+	}
+	if len(removed) > 0 || len(added) > 0 {
+		add(changeMatchers, removed, added)
+	}
+
+	if a.OriginalOffset != b.OriginalOffset {
+		add(changeOffset, a.OriginalOffset.Milliseconds(), b.OriginalOffset.Milliseconds())
+	}
+	diffAt(a.Timestamp, b.Timestamp, a.StartOrEnd, b.StartOrEnd, add)
+}
+
+func diffAt(tsA, tsB *int64, seA, seB parser.ItemType, add func(kind string, oldVal, newVal interface{})) {
+	at := func(ts *int64, se parser.ItemType) interface{} {
+		if ts != nil {
+			return *ts
+		}
+		return getStartOrEnd(se)
+	}
+	if oldAt, newAt := at(tsA, seA), at(tsB, seB); oldAt != newAt {
+		add(changeAt, oldAt, newAt)
+	}
+}
+
+// HandleDiff parses the "old" and "new" expressions and responds with both
+// ASTs and a list of structural changes between them.
+func HandleDiff(features Features) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prom_httputil.SetCORS(w, CORSOrigin, r)
+		oldInput, newInput := r.FormValue("old"), r.FormValue("new")
+		reqFeatures, err := ParseFeatures(r.Form["enable_feature"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		features := features.Merge(reqFeatures)
+
+		oldExpr, err := ParseExpr(oldInput, features)
+		if err != nil {
+			writeError(w, map[string]string{"type": "error", "message": fmt.Sprintf("Old expression incomplete or buggy: %v", err)})
+			return
+		}
+		newExpr, err := ParseExpr(newInput, features)
+		if err != nil {
+			writeError(w, map[string]string{"type": "error", "message": fmt.Sprintf("New expression incomplete or buggy: %v", err)})
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"old":     translateAST(oldExpr),
+			"new":     translateAST(newExpr),
+			"changes": diffAST(oldExpr, newExpr),
+		})
+	}
+}
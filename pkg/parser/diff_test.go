@@ -0,0 +1,149 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDiffAST(t *testing.T) {
+	for _, tc := range []struct {
+		old, new string
+		want     []change
+	}{
+		{old: `sum(rate(x[5m]))`, new: `sum(rate(x[5m]))`, want: []change{}},
+		// Formatting and matcher order don't matter.
+		{old: `x{a="1",b="2"}`, new: `x{b="2", a="1"}`, want: []change{}},
+		{old: `sum by (a, b) (x)`, new: `sum by (b, a) (x)`, want: []change{}},
+		{
+			old:  `sum(x)`,
+			new:  `max(x)`,
+			want: []change{{Kind: changeOperator, Old: "sum", New: "max"}},
+		},
+		{
+			old: `sum by (job) (x)`,
+			new: `sum without (job) (x)`,
+			want: []change{{
+				Kind: changeGrouping,
+				Old:  groupingChange{Grouping: []string{"job"}},
+				New:  groupingChange{Grouping: []string{"job"}, Without: true},
+			}},
+		},
+		{
+			old: `rate(x{job="a"}[5m])`,
+			new: `irate(y{job="b"}[1m])`,
+			want: []change{
+				{Kind: changeFunction, Old: "rate", New: "irate"},
+				{Path: "args[0]", Kind: changeMetricName, Old: "x", New: "y"},
+				{Path: "args[0]", Kind: changeMatchers, Old: []string{`job="a"`}, New: []string{`job="b"`}},
+				{Path: "args[0]", Kind: changeRange, Old: int64(300000), New: int64(60000)},
+			},
+		},
+		{
+			old:  `x offset 5m`,
+			new:  `x @ end()`,
+			want: []change{{Kind: changeOffset, Old: int64(300000), New: int64(0)}, {Kind: changeAt, Old: nil, New: "end"}},
+		},
+		{
+			old:  `max_over_time(x[1h:1m])`,
+			new:  `max_over_time(x[1h:5m])`,
+			want: []change{{Path: "args[0]", Kind: changeStep, Old: int64(60000), New: int64(300000)}},
+		},
+		{
+			old:  `x / y`,
+			new:  `x / on (job) y`,
+			want: []change{{Kind: changeMatching, Old: &matchingChange{Card: "one-to-one", Labels: []string{}, Include: []string{}}, New: &matchingChange{Card: "one-to-one", Labels: []string{"job"}, On: true, Include: []string{}}}},
+		},
+		{
+			old:  `x > 1`,
+			new:  `x > bool 2`,
+			want: []change{{Kind: changeMatching, Old: nil, New: &matchingChange{Bool: true, Labels: []string{}, Include: []string{}}}, {Path: "rhs", Kind: changeValue, Old: "1", New: "2"}},
+		},
+		{
+			old:  `x`,
+			new:  `sum(x)`,
+			want: []change{{Kind: changeAdded, New: "sum(x)"}},
+		},
+		{
+			old:  `sum(x)`,
+			new:  `x`,
+			want: []change{{Kind: changeRemoved, Old: "sum(x)"}},
+		},
+		{
+			old:  `x`,
+			new:  `1`,
+			want: []change{{Kind: changeReplaced, Old: "x", New: "1"}},
+		},
+		{
+			old:  `topk(5, x)`,
+			new:  `topk(10, x)`,
+			want: []change{{Path: "param", Kind: changeValue, Old: "5", New: "10"}},
+		},
+		{
+			old:  `round(x)`,
+			new:  `round(x, 10)`,
+			want: []change{{Path: "args[1]", Kind: changeAdded, New: "10"}},
+		},
+		{
+			old:  `label_replace(x, "a", "$1", "b", "(.*)")`,
+			new:  `label_replace(x, "c", "$1", "b", "(.*)")`,
+			want: []change{{Path: "args[1]", Kind: changeValue, Old: "a", New: "c"}},
+		},
+		{
+			old:  `-(x + y)`,
+			new:  `-(x - y)`,
+			want: []change{{Path: "expr.expr", Kind: changeOperator, Old: "+", New: "-"}},
+		},
+	} {
+		t.Run(tc.old+" -> "+tc.new, func(t *testing.T) {
+			oldExpr, err := ParseExpr(tc.old, Features{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			newExpr, err := ParseExpr(tc.new, Features{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(diffAST(oldExpr, newExpr))
+			want, _ := json.Marshal(tc.want)
+			if string(got) != string(want) {
+				t.Errorf("got changes\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleDiff(t *testing.T) {
+	for _, tc := range []struct {
+		old, new string
+		status   int
+		want     string
+	}{
+		{old: "x", new: "y", status: http.StatusOK, want: `"kind":"metricNameChanged"`},
+		{old: "x(", new: "y", status: http.StatusBadRequest, want: "Old expression incomplete or buggy"},
+		{old: "x", new: "y(", status: http.StatusBadRequest, want: "New expression incomplete or buggy"},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/diff?"+url.Values{"old": {tc.old}, "new": {tc.new}}.Encode(), nil)
+		HandleDiff(Features{})(rec, req)
+		if rec.Code != tc.status || !strings.Contains(rec.Body.String(), tc.want) {
+			t.Errorf("%q -> %q: got status %d and body %q, want status %d and body containing %q", tc.old, tc.new, rec.Code, rec.Body.String(), tc.status, tc.want)
+		}
+	}
+}
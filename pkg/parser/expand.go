@@ -0,0 +1,144 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/prometheus/promlens/pkg/rules"
+)
+
+const (
+	// maxExpansionDepth limits how many levels of nested recording rules are expanded.
+	maxExpansionDepth = 10
+	// maxExpansions limits the total number of recording rule expansions in a
+	// single request, since rules that refer to several other recorded metrics
+	// can make the number of expansions grow exponentially with the depth.
+	maxExpansions = 10000
+)
+
+// ruleExpander expands recording rules in translated ASTs. It is created once
+// per request, so that all expressions of a batch request share the same
+// expansion limit and parsed rule expressions.
+type ruleExpander struct {
+	rm       *rules.Manager
+	features Features
+	// remaining is the number of expansions left until maxExpansions is reached.
+	remaining int
+	// parsed caches the parsed expressions of recording rules by expression.
+	parsed map[string]parsedRule
+}
+
+type parsedRule struct {
+	expr parser.Expr
+	err  error
+}
+
+func newRuleExpander(rm *rules.Manager, features Features) *ruleExpander {
+	return &ruleExpander{
+		rm:        rm,
+		features:  features,
+		remaining: maxExpansions,
+		parsed:    map[string]parsedRule{},
+	}
+}
+
+// expand adds an "expansions" field to every selector in a translated AST
+// whose metric name is recorded by one or more recording rules. Each expansion
+// describes one such rule and contains the translated AST of the rule's
+// expression, which is expanded recursively in turn. It returns an error if
+// the request exceeds the maximum number of expansions.
+func (e *ruleExpander) expand(ast interface{}) error {
+	return e.expandNode(ast, map[string]bool{})
+}
+
+// expandNode expands the selectors below node. The names of the metrics that
+// are currently being expanded are tracked in "expanding" to detect cycles.
+func (e *ruleExpander) expandNode(node interface{}, expanding map[string]bool) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if t := n["type"]; t == "vectorSelector" || t == "matrixSelector" {
+			name, _ := n["name"].(string)
+			if rrs := e.rm.RecordingRules(name); len(rrs) > 0 {
+				exps, err := e.expandRule(name, rrs, expanding)
+				if err != nil {
+					return err
+				}
+				n["expansions"] = exps
+			}
+			return nil
+		}
+		for _, c := range n {
+			if err := e.expandNode(c, expanding); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, c := range n {
+			if err := e.expandNode(c, expanding); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *ruleExpander) expandRule(name string, rrs []rules.RecordingRule, expanding map[string]bool) ([]map[string]interface{}, error) {
+	expansions := make([]map[string]interface{}, 0, len(rrs))
+	for _, rr := range rrs {
+		if e.remaining <= 0 {
+			return nil, fmt.Errorf("maximum number of %d recording rule expansions exceeded", maxExpansions)
+		}
+		e.remaining--
+
+		exp := map[string]interface{}{
+			"record": rr.Record,
+			"group":  rr.Group,
+			"file":   rr.File,
+			"labels": rr.Labels,
+			"expr":   rr.Expr,
+		}
+		expansions = append(expansions, exp)
+
+		switch {
+		case expanding[name]:
+			exp["error"] = fmt.Sprintf("recording rule for %q refers to itself", name)
+			continue
+		case len(expanding) >= maxExpansionDepth:
+			exp["error"] = fmt.Sprintf("maximum recording rule expansion depth of %d exceeded", maxExpansionDepth)
+			continue
+		}
+
+		pr, ok := e.parsed[rr.Expr]
+		if !ok {
+			pr.expr, pr.err = ParseExpr(rr.Expr, e.features)
+			e.parsed[rr.Expr] = pr
+		}
+		if pr.err != nil {
+			exp["error"] = fmt.Sprintf("error parsing recording rule expression: %v", pr.err)
+			continue
+		}
+		ast := translateAST(pr.expr)
+		expanding[name] = true
+		err := e.expandNode(ast, expanding)
+		delete(expanding, name)
+		if err != nil {
+			return nil, err
+		}
+		exp["ast"] = ast
+	}
+	return expansions, nil
+}
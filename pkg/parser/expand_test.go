@@ -0,0 +1,330 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/promlens/pkg/rules"
+)
+
+const expandRuleFile = `
+groups:
+  - name: example
+    rules:
+      - record: job:requests:rate5m
+        expr: sum by (job) (instance:requests:rate5m)
+      - record: instance:requests:rate5m
+        expr: rate(requests_total[5m])
+      - record: two_sources
+        expr: a
+      - record: two_sources
+        expr: b
+      - record: self
+        expr: self + 1
+      - record: ping
+        expr: pong
+      - record: pong
+        expr: ping
+      - record: experimental
+        expr: sort_by_label(x, "job")
+`
+
+func newTestRuleManager(t *testing.T, ruleFile string) *rules.Manager {
+	t.Helper()
+	fn := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(fn, []byte(ruleFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rm, err := rules.NewManager(slog.New(slog.DiscardHandler), []string{fn}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rm
+}
+
+// expansionSummary renders the selectors in a translated AST and their
+// recording rule expansions in a compact form, like "a{b{c}}" for a selector
+// "a" whose recording rule selects "b", which is in turn recorded from "c".
+// Expansion errors are rendered as "!<error>".
+func expansionSummary(node interface{}) string {
+	var selectors []string
+	var walk func(n interface{})
+	walk = func(n interface{}) {
+		switch n := n.(type) {
+		case map[string]interface{}:
+			if t := n["type"]; t == "vectorSelector" || t == "matrixSelector" {
+				s := fmt.Sprint(n["name"])
+				// Expansions are []map[string]interface{} when taken directly from
+				// ruleExpander.expand() and []interface{} after a JSON round trip.
+				var exps []map[string]interface{}
+				switch e := n["expansions"].(type) {
+				case []map[string]interface{}:
+					exps = e
+				case []interface{}:
+					for _, exp := range e {
+						exps = append(exps, exp.(map[string]interface{}))
+					}
+				}
+				if exps != nil {
+					var parts []string
+					for _, exp := range exps {
+						if err, ok := exp["error"]; ok {
+							parts = append(parts, fmt.Sprintf("!%v", err))
+							continue
+						}
+						parts = append(parts, expansionSummary(exp["ast"]))
+					}
+					s += "{" + strings.Join(parts, "|") + "}"
+				}
+				selectors = append(selectors, s)
+				return
+			}
+			keys := make([]string, 0, len(n))
+			for k := range n {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(n[k])
+			}
+		case []interface{}:
+			for _, c := range n {
+				walk(c)
+			}
+		}
+	}
+	walk(node)
+	return strings.Join(selectors, " ")
+}
+
+func TestExpandRecordingRules(t *testing.T) {
+	rm := newTestRuleManager(t, expandRuleFile)
+
+	for _, tc := range []struct {
+		expr     string
+		features Features
+		want     string
+	}{
+		{
+			expr: `not_recorded`,
+			want: `not_recorded`,
+		},
+		{
+			expr: `instance:requests:rate5m`,
+			want: `instance:requests:rate5m{requests_total}`,
+		},
+		{
+			// Recording rules are expanded recursively.
+			expr: `job:requests:rate5m > 10`,
+			want: `job:requests:rate5m{instance:requests:rate5m{requests_total}}`,
+		},
+		{
+			// Range selectors are expanded as well.
+			expr: `max_over_time(instance:requests:rate5m[1h])`,
+			want: `instance:requests:rate5m{requests_total}`,
+		},
+		{
+			// A metric can be recorded by several rules.
+			expr: `two_sources`,
+			want: `two_sources{a|b}`,
+		},
+		{
+			expr: `self`,
+			want: `self{self{!recording rule for "self" refers to itself}}`,
+		},
+		{
+			expr: `ping`,
+			want: `ping{pong{ping{!recording rule for "ping" refers to itself}}}`,
+		},
+		{
+			// Rule expressions are parsed with the features of the request.
+			expr: `experimental`,
+			want: `experimental{!error parsing recording rule expression: 1:1: parse error: function "sort_by_label" is not enabled}`,
+		},
+		{
+			expr:     `experimental`,
+			features: Features{ExperimentalFunctions: true},
+			want:     `experimental{x}`,
+		},
+	} {
+		expr, err := ParseExpr(tc.expr, tc.features)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+		ast := translateAST(expr)
+		if err := newRuleExpander(rm, tc.features).expand(ast); err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+		if got := expansionSummary(ast); got != tc.want {
+			t.Errorf("%s: expected expansions %q, got %q", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestExpandRecordingRulesMaxDepth(t *testing.T) {
+	var rf strings.Builder
+	rf.WriteString("groups:\n  - name: chain\n    rules:\n")
+	for i := 0; i <= maxExpansionDepth; i++ {
+		fmt.Fprintf(&rf, "      - record: level%d\n        expr: level%d\n", i, i+1)
+	}
+	rm := newTestRuleManager(t, rf.String())
+
+	expr, err := ParseExpr("level0", Features{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast := translateAST(expr)
+	if err := newRuleExpander(rm, Features{}).expand(ast); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "level0"
+	for i := 1; i <= maxExpansionDepth; i++ {
+		want += fmt.Sprintf("{level%d", i)
+	}
+	want += fmt.Sprintf("{!maximum recording rule expansion depth of %d exceeded}", maxExpansionDepth)
+	want += strings.Repeat("}", maxExpansionDepth)
+	if got := expansionSummary(ast); got != want {
+		t.Errorf("expected expansions %q, got %q", want, got)
+	}
+}
+
+func TestHandleExpandRules(t *testing.T) {
+	rm := newTestRuleManager(t, expandRuleFile)
+
+	for _, tc := range []struct {
+		params url.Values
+		rm     *rules.Manager
+		status int
+		want   string
+	}{
+		{
+			params: url.Values{"expr": {"instance:requests:rate5m"}},
+			rm:     rm,
+			status: http.StatusOK,
+			want:   "instance:requests:rate5m",
+		},
+		{
+			params: url.Values{"expr": {"instance:requests:rate5m"}, "expand_rules": {"true"}},
+			rm:     rm,
+			status: http.StatusOK,
+			want:   "instance:requests:rate5m{requests_total}",
+		},
+		{
+			params: url.Values{"expr": {"instance:requests:rate5m"}, "expand_rules": {"true"}},
+			status: http.StatusBadRequest,
+			want:   "no rule files are configured",
+		},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/parse?"+tc.params.Encode(), nil)
+		w := httptest.NewRecorder()
+		Handle(Features{}, tc.rm)(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%v: expected status %d, got %d: %s", tc.params, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.status != http.StatusOK {
+			if !strings.Contains(w.Body.String(), tc.want) {
+				t.Errorf("%v: expected error containing %q, got %q", tc.params, tc.want, w.Body.String())
+			}
+			continue
+		}
+
+		var ast interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &ast); err != nil {
+			t.Fatal(err)
+		}
+		if got := expansionSummary(ast); got != tc.want {
+			t.Errorf("%v: expected expansions %q, got %q", tc.params, tc.want, got)
+		}
+	}
+}
+
+func TestExpandRecordingRulesLimit(t *testing.T) {
+	// Each rule refers to the next recorded metric three times, so expanding
+	// wide0 without a limit would result in more than 3^maxExpansionDepth
+	// expansions.
+	var rf strings.Builder
+	rf.WriteString("groups:\n  - name: wide\n    rules:\n")
+	for i := 0; i <= maxExpansionDepth; i++ {
+		fmt.Fprintf(&rf, "      - record: wide%d\n        expr: wide%d + wide%d * wide%d\n", i, i+1, i+1, i+1)
+	}
+	rm := newTestRuleManager(t, rf.String())
+
+	for _, tc := range []struct {
+		expr    string
+		wantErr bool
+	}{
+		// 3^0 + ... + 3^5 = 364 expansions.
+		{expr: "wide5"},
+		{expr: "wide0", wantErr: true},
+		{expr: "sum(rate(wide1[5m]))", wantErr: true},
+	} {
+		expr, err := ParseExpr(tc.expr, Features{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = newRuleExpander(rm, Features{}).expand(translateAST(expr))
+		if tc.wantErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tc.expr, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "maximum number of") {
+			t.Errorf("%s: unexpected error %v", tc.expr, err)
+		}
+	}
+
+	// The limit applies to all expressions of a batch request together.
+	exprs := []string{"up"}
+	for range maxExpansions/364 + 1 {
+		exprs = append(exprs, "wide5")
+	}
+	exprs = append(exprs, "up")
+	body, err := json.Marshal(exprs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	HandleBatch(Features{}, rm)(w, httptest.NewRequest(http.MethodPost, "/api/parse_batch?expand_rules=true", strings.NewReader(string(body))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var results []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(exprs) {
+		t.Fatalf("expected %d results, got %d", len(exprs), len(results))
+	}
+	// Selectors of metrics without recording rules don't count towards the limit.
+	for _, i := range []int{0, 1, len(results) - 1} {
+		if results[i]["type"] != "vectorSelector" {
+			t.Errorf("result %d: expected a selector, got %v", i, results[i])
+		}
+	}
+	if last := results[len(results)-2]; last["type"] != "error" || !strings.Contains(last["message"].(string), "maximum number of") {
+		t.Errorf("expected expansion limit error for last expansion, got %v", last)
+	}
+}
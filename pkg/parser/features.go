@@ -0,0 +1,126 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// FeatureExperimentalFunctions enables experimental PromQL functions and
+// aggregations. The name matches the equivalent Prometheus --enable-feature value.
+const FeatureExperimentalFunctions = "promql-experimental-functions"
+
+// AvailableFeatures lists all parser features that can be enabled.
+var AvailableFeatures = []string{FeatureExperimentalFunctions}
+
+func init() {
+	// The Prometheus parser only knows a process-wide switch for experimental
+	// functions, so we always enable it and check the features that are active
+	// for a given parse request after parsing instead (see checkFeatures()).
+	//
+	// Note that this affects every user of the Prometheus parser in the process
+	// that imports this package, including rulefmt. Expressions must therefore
+	// always be validated with ParseExpr (or checkFeatures), even if they were
+	// already parsed by other Prometheus packages.
+	parser.EnableExperimentalFunctions = true
+}
+
+// Features configures which experimental PromQL parser features are enabled.
+type Features struct {
+	ExperimentalFunctions bool
+}
+
+// ParseFeatures parses a list of feature names (each of which may also be a
+// comma-separated list) into a Features struct.
+func ParseFeatures(names []string) (Features, error) {
+	var f Features
+	for _, n := range names {
+		for _, name := range strings.Split(n, ",") {
+			switch strings.TrimSpace(name) {
+			case FeatureExperimentalFunctions:
+				f.ExperimentalFunctions = true
+			case "":
+			default:
+				return Features{}, fmt.Errorf("unknown parser feature %q, valid features are: %s", name, strings.Join(AvailableFeatures, ", "))
+			}
+		}
+	}
+	return f, nil
+}
+
+// Names returns the names of all enabled features.
+func (f Features) Names() []string {
+	names := []string{}
+	if f.ExperimentalFunctions {
+		names = append(names, FeatureExperimentalFunctions)
+	}
+	return names
+}
+
+// Merge returns the union of two feature sets.
+func (f Features) Merge(other Features) Features {
+	return Features{
+		ExperimentalFunctions: f.ExperimentalFunctions || other.ExperimentalFunctions,
+	}
+}
+
+// ParseExpr parses a PromQL expression with the given features enabled.
+func ParseExpr(input string, features Features) (parser.Expr, error) {
+	expr, err := parser.ParseExpr(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFeatures(input, expr, features); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// checkFeatures returns an error in the same format as the Prometheus parser
+// if the expression uses any features that are not enabled.
+func checkFeatures(input string, expr parser.Expr, features Features) error {
+	if features.ExperimentalFunctions {
+		return nil
+	}
+
+	var err error
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.Call:
+			if n.Func.Experimental {
+				err = &parser.ParseErr{
+					PositionRange: n.PositionRange(),
+					Err:           fmt.Errorf("function %q is not enabled", n.Func.Name),
+					Query:         input,
+				}
+				return err
+			}
+		case *parser.AggregateExpr:
+			if n.Op == parser.LIMITK || n.Op == parser.LIMIT_RATIO {
+				err = &parser.ParseErr{
+					PositionRange: n.PositionRange(),
+					Err:           errors.New("limitk() and limit_ratio() are experimental and must be enabled with the " + FeatureExperimentalFunctions + " feature"),
+					Query:         input,
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFeatures(t *testing.T) {
+	for _, tc := range []struct {
+		names   []string
+		want    Features
+		wantErr bool
+	}{
+		{names: nil},
+		{names: []string{""}},
+		{names: []string{FeatureExperimentalFunctions}, want: Features{ExperimentalFunctions: true}},
+		{names: []string{" promql-experimental-functions ,"}, want: Features{ExperimentalFunctions: true}},
+		{names: []string{"foo"}, wantErr: true},
+		{names: []string{FeatureExperimentalFunctions + ",foo"}, wantErr: true},
+	} {
+		got, err := ParseFeatures(tc.names)
+		if tc.wantErr != (err != nil) {
+			t.Errorf("ParseFeatures(%q): unexpected error %v", tc.names, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseFeatures(%q) = %+v, want %+v", tc.names, got, tc.want)
+		}
+	}
+
+	f := Features{}.Merge(Features{ExperimentalFunctions: true})
+	if !slices.Equal(f.Names(), []string{FeatureExperimentalFunctions}) {
+		t.Errorf("unexpected feature names %v", f.Names())
+	}
+}
+
+func TestParseExprFeatures(t *testing.T) {
+	for _, tc := range []struct {
+		expr         string
+		experimental bool
+	}{
+		{expr: `sort_by_label(x, "job")`, experimental: true},
+		{expr: `mad_over_time(x[5m])`, experimental: true},
+		{expr: `limitk(5, x)`, experimental: true},
+		{expr: `limit_ratio(0.5, x)`, experimental: true},
+		// Experimental functions are also found below other nodes.
+		{expr: `sum(rate(x[5m])) / sort_by_label(y, "job")`, experimental: true},
+		{expr: `sort(x)`},
+		{expr: `topk(5, x)`},
+	} {
+		_, err := ParseExpr(tc.expr, Features{})
+		if tc.experimental != (err != nil) {
+			t.Errorf("ParseExpr(%s) without features: unexpected error %v", tc.expr, err)
+		}
+		if _, err := ParseExpr(tc.expr, Features{ExperimentalFunctions: true}); err != nil {
+			t.Errorf("ParseExpr(%s) with experimental functions: unexpected error %v", tc.expr, err)
+		}
+	}
+}
+
+func TestExportRuleFeatures(t *testing.T) {
+	req := ruleExportRequest{Record: "job:x:sorted", Expr: `sort_by_label(x, "job")`}
+	if _, errs := exportRule(req, Features{}); len(errs) != 1 || !strings.Contains(errs[0].Error(), "is not enabled") {
+		t.Errorf("expected rule with experimental function to be rejected, got %v", errs)
+	}
+	if _, errs := exportRule(req, Features{ExperimentalFunctions: true}); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	prom_httputil "github.com/prometheus/prometheus/util/httputil"
+)
+
+type function struct {
+	Name         string             `json:"name"`
+	ArgTypes     []parser.ValueType `json:"argTypes"`
+	Variadic     int                `json:"variadic"`
+	ReturnType   parser.ValueType   `json:"returnType"`
+	Experimental bool               `json:"experimental"`
+}
+
+// functions returns the signatures of all PromQL functions known to the parser, sorted by name.
+func functions() []function {
+	fns := make([]function, 0, len(parser.Functions))
+	for _, fn := range parser.Functions {
+		argTypes := fn.ArgTypes
+		if argTypes == nil {
+			argTypes = []parser.ValueType{}
+		}
+		fns = append(fns, function{
+			Name:         fn.Name,
+			ArgTypes:     argTypes,
+			Variadic:     fn.Variadic,
+			ReturnType:   fn.ReturnType,
+			Experimental: fn.Experimental,
+		})
+	}
+	sort.Slice(fns, func(i, j int) bool {
+		return fns[i].Name < fns[j].Name
+	})
+	return fns
+}
+
+// HandleFunctions responds with the signatures of all PromQL functions
+// supported by the parser that PromLens uses.
+func HandleFunctions(w http.ResponseWriter, r *http.Request) {
+	prom_httputil.SetCORS(w, CORSOrigin, r)
+	buf, err := json.Marshal(functions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error marshaling functions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf)
+}
@@ -14,15 +14,22 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/grafana/regexp"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 	prom_httputil "github.com/prometheus/prometheus/util/httputil"
+
+	"github.com/prometheus/promlens/pkg/rules"
 )
 
 func getStartOrEnd(startOrEnd parser.ItemType) interface{} {
@@ -157,26 +164,214 @@ func translateMatchers(in []*labels.Matcher) interface{} {
 	return out
 }
 
-func Handle(w http.ResponseWriter, r *http.Request) {
-	expr, err := parser.ParseExpr(r.FormValue("expr"))
+const (
+	// maxBatchSize is the maximum number of expressions in a single batch parse request.
+	maxBatchSize = 1000
+	// maxBatchBodySize is the maximum size of a batch parse request body.
+	maxBatchBodySize = 4 * 1024 * 1024
+)
+
+// CORSOrigin matches the origins that may access the API endpoints
+// cross-origin, which are all of them.
+var CORSOrigin = regexp.MustCompile("^(?:.*)$")
+
+func parseError(err error) map[string]string {
+	return map[string]string{"type": "error", "message": fmt.Sprintf("Expression incomplete or buggy: %v", err)}
+}
+
+// writeError responds with a JSON error object (like the one returned by
+// parseError) and a 400 status code.
+func writeError(w http.ResponseWriter, errObj map[string]string) {
+	errJSON, err := json.Marshal(errObj)
 	if err != nil {
-		errJSON, err := json.Marshal(map[string]string{"type": "error", "message": fmt.Sprintf("Expression incomplete or buggy: %v", err)})
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Error marshaling error JSON: %v", err), http.StatusInternalServerError)
-			return
-		}
-		http.Error(w, string(errJSON), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Error marshaling error JSON: %v", err), http.StatusInternalServerError)
 		return
 	}
-	regex, err := regexp.Compile("^(?:.*)$")
-	if err != nil {
-		panic(err)
-	}
-	prom_httputil.SetCORS(w, regex, r)
-	buf, err := json.Marshal(translateAST(expr))
+	http.Error(w, string(errJSON), http.StatusBadRequest)
+}
+
+// writeJSON responds with the JSON encoding of v.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	buf, err := json.Marshal(v)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error marshaling AST: %v", err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Error marshaling response: %v", err), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.Write(buf)
 }
+
+// parseOptions controls how the parse endpoints parse and translate expressions.
+type parseOptions struct {
+	features Features
+	// templateVars enables the replacement of Grafana template variables,
+	// using templateVarValues for variables that have a value.
+	templateVars      bool
+	templateVarValues map[string]string
+	// expandRules enables the expansion of recording rules with expander.
+	expandRules bool
+	expander    *ruleExpander
+}
+
+// newParseOptions reads the per-request parse options from the provided
+// request parameters and merges them with the server-wide features.
+func newParseOptions(params url.Values, features Features, rm *rules.Manager) (parseOptions, error) {
+	reqFeatures, err := ParseFeatures(params["enable_feature"])
+	if err != nil {
+		return parseOptions{}, err
+	}
+	opts := parseOptions{
+		features:          features.Merge(reqFeatures),
+		templateVarValues: map[string]string{},
+	}
+
+	for _, b := range []struct {
+		param string
+		dst   *bool
+	}{
+		{"grafana_vars", &opts.templateVars},
+		{"expand_rules", &opts.expandRules},
+	} {
+		if v := params.Get(b.param); v != "" {
+			if *b.dst, err = strconv.ParseBool(v); err != nil {
+				return parseOptions{}, fmt.Errorf("invalid value %q for parameter %q", v, b.param)
+			}
+		}
+	}
+	if opts.expandRules {
+		if rm == nil {
+			return parseOptions{}, errors.New("cannot expand recording rules, no rule files are configured in this PromLens instance")
+		}
+		opts.expander = newRuleExpander(rm, opts.features)
+	}
+	for k, v := range params {
+		if name, ok := strings.CutPrefix(k, "var-"); ok && len(v) > 0 {
+			opts.templateVarValues[name] = v[0]
+		}
+	}
+	return opts, nil
+}
+
+// parse parses an expression and translates it into its JSON AST representation.
+func (o parseOptions) parse(input string) (interface{}, error) {
+	var ast interface{}
+	if o.templateVars {
+		tv := newTemplateVars(o.templateVarValues)
+		expr, err := tv.parse(input, o.features)
+		if err != nil {
+			return nil, errors.New(tv.restoreString(err.Error()))
+		}
+		ast = translateAST(expr)
+		tv.restore(ast)
+	} else {
+		expr, err := ParseExpr(input, o.features)
+		if err != nil {
+			return nil, err
+		}
+		ast = translateAST(expr)
+	}
+
+	if o.expandRules {
+		if err := o.expander.expand(ast); err != nil {
+			return nil, err
+		}
+	}
+	return ast, nil
+}
+
+// Handle parses a single expression and responds with its AST. Additional
+// parser features can be enabled per request via the "enable_feature" parameter,
+// and Grafana template variables can be allowed via the "grafana_vars" and
+// "var-<name>" parameters. Recording rules from the loaded rule files are
+// expanded when the "expand_rules" parameter is set.
+func Handle(features Features, rm *rules.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input := r.FormValue("expr")
+		opts, err := newParseOptions(r.Form, features, rm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ast, err := opts.parse(input)
+		if err != nil {
+			errJSON, err := json.Marshal(parseError(err))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error marshaling error JSON: %v", err), http.StatusInternalServerError)
+				return
+			}
+			http.Error(w, string(errJSON), http.StatusBadRequest)
+			return
+		}
+		prom_httputil.SetCORS(w, CORSOrigin, r)
+		buf, err := json.Marshal(ast)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error marshaling AST: %v", err), http.StatusBadRequest)
+			return
+		}
+		w.Write(buf)
+	}
+}
+
+// HandleBatch parses a JSON array of expressions from the request body and
+// responds with a JSON array of the same length, containing either the AST
+// or an error object for each expression. It supports the same options as
+// Handle, but as URL parameters.
+func HandleBatch(features Features, rm *rules.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prom_httputil.SetCORS(w, CORSOrigin, r)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid HTTP method, use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		opts, err := newParseOptions(r.URL.Query(), features, rm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body bytes.Buffer
+		_, err = io.Copy(&body, io.LimitReader(r.Body, maxBatchBodySize+1))
+		_ = r.Body.Close()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error reading request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if body.Len() > maxBatchBodySize {
+			http.Error(w, fmt.Sprintf("Request body exceeds maximum size of %d bytes", maxBatchBodySize), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		var exprs []string
+		if err := json.Unmarshal(body.Bytes(), &exprs); err != nil {
+			http.Error(w, fmt.Sprintf("Error unmarshaling expressions, expected a JSON array of strings: %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(exprs) > maxBatchSize {
+			http.Error(w, fmt.Sprintf("Too many expressions in batch (%d), the maximum is %d", len(exprs), maxBatchSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		results := make([]interface{}, 0, len(exprs))
+		for _, e := range exprs {
+			ast, err := opts.parse(e)
+			if err != nil {
+				results = append(results, parseError(err))
+				continue
+			}
+			results = append(results, ast)
+		}
+
+		buf, err := json.Marshal(results)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error marshaling ASTs: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(buf)
+	}
+}
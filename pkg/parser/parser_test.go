@@ -0,0 +1,163 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleBatch(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		method   string
+		query    string
+		body     string
+		features Features
+		status   int
+		// results holds the expected "type" of each result, or "error" followed
+		// by a substring of the expected error message.
+		results []string
+		errMsg  string
+	}{
+		{
+			name:    "valid and invalid expressions",
+			body:    `["up", "sum(rate(x[5m]))", "sum(", "1 + 2"]`,
+			status:  http.StatusOK,
+			results: []string{"vectorSelector", "aggregation", "error: unclosed left parenthesis", "binaryExpr"},
+		},
+		{
+			name:    "empty batch",
+			body:    `[]`,
+			status:  http.StatusOK,
+			results: []string{},
+		},
+		{
+			name:    "experimental function without feature",
+			body:    `["sort_by_label(x, \"job\")"]`,
+			status:  http.StatusOK,
+			results: []string{`error: function "sort_by_label" is not enabled`},
+		},
+		{
+			name:    "experimental function enabled per request",
+			query:   "enable_feature=" + FeatureExperimentalFunctions,
+			body:    `["sort_by_label(x, \"job\")"]`,
+			status:  http.StatusOK,
+			results: []string{"call"},
+		},
+		{
+			name:     "experimental function enabled for server",
+			body:     `["sort_by_label(x, \"job\")"]`,
+			features: Features{ExperimentalFunctions: true},
+			status:   http.StatusOK,
+			results:  []string{"call"},
+		},
+		{
+			name:    "template variables",
+			query:   "grafana_vars=true",
+			body:    `["rate(x{job=\"$job\"}[$__rate_interval])"]`,
+			status:  http.StatusOK,
+			results: []string{"call"},
+		},
+		{
+			name:   "preflight request",
+			method: http.MethodOptions,
+			status: http.StatusOK,
+		},
+		{
+			name:   "wrong method",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			errMsg: "use POST",
+		},
+		{
+			name:   "unknown feature",
+			query:  "enable_feature=foo",
+			body:   `["up"]`,
+			status: http.StatusBadRequest,
+			errMsg: "foo",
+		},
+		{
+			name:   "invalid boolean parameter",
+			query:  "grafana_vars=maybe",
+			body:   `["up"]`,
+			status: http.StatusBadRequest,
+			errMsg: `invalid value "maybe" for parameter "grafana_vars"`,
+		},
+		{
+			name:   "body is not an array of strings",
+			body:   `{"expr": "up"}`,
+			status: http.StatusBadRequest,
+			errMsg: "expected a JSON array of strings",
+		},
+		{
+			name:   "too many expressions",
+			body:   `[` + strings.Repeat(`"up",`, maxBatchSize) + `"up"]`,
+			status: http.StatusRequestEntityTooLarge,
+			errMsg: "Too many expressions",
+		},
+		{
+			name:   "body too large",
+			body:   `["` + strings.Repeat("a", maxBatchBodySize) + `"]`,
+			status: http.StatusRequestEntityTooLarge,
+			errMsg: "exceeds maximum size",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, "/api/parse_batch?"+tc.query, strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			HandleBatch(tc.features, nil)(w, req)
+
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.errMsg != "" {
+				if !strings.Contains(w.Body.String(), tc.errMsg) {
+					t.Errorf("expected error containing %q, got %q", tc.errMsg, w.Body.String())
+				}
+				return
+			}
+			if tc.results == nil {
+				return
+			}
+
+			var results []map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(tc.results) {
+				t.Fatalf("expected %d results, got %d: %s", len(tc.results), len(results), w.Body.String())
+			}
+			for i, want := range tc.results {
+				if errMsg, ok := strings.CutPrefix(want, "error: "); ok {
+					msg, _ := results[i]["message"].(string)
+					if results[i]["type"] != "error" || !strings.Contains(msg, errMsg) {
+						t.Errorf("result %d: expected error containing %q, got %v", i, errMsg, results[i])
+					}
+					continue
+				}
+				if results[i]["type"] != want {
+					t.Errorf("result %d: expected node type %q, got %v", i, want, results[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	prom_httputil "github.com/prometheus/prometheus/util/httputil"
+	"go.yaml.in/yaml/v2"
+)
+
+// maxRuleBodySize is the maximum size of a rule export request body.
+const maxRuleBodySize = 1024 * 1024
+
+// ruleExportRequest describes a rule to export, as sent by the client.
+type ruleExportRequest struct {
+	Group         string            `json:"group"`
+	Interval      model.Duration    `json:"interval"`
+	Record        string            `json:"record"`
+	Alert         string            `json:"alert"`
+	Expr          string            `json:"expr"`
+	For           model.Duration    `json:"for"`
+	KeepFiringFor model.Duration    `json:"keepFiringFor"`
+	Labels        map[string]string `json:"labels"`
+	Annotations   map[string]string `json:"annotations"`
+}
+
+type ruleGroupsFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name     string         `yaml:"name"`
+	Interval model.Duration `yaml:"interval,omitempty"`
+	Rules    []rulefmt.Rule `yaml:"rules"`
+}
+
+// exportRule turns a rule export request into a rule group YAML snippet and
+// validates it in the same way that Prometheus validates rule files.
+func exportRule(req ruleExportRequest, features Features) ([]byte, []error) {
+	var errs []error
+	if req.Record == "" && req.Alert == "" {
+		errs = append(errs, errors.New("one of 'record' or 'alert' must be set"))
+	}
+	// Check the features here, since rulefmt accepts all experimental
+	// functions (see init() in features.go).
+	if _, err := ParseExpr(req.Expr, features); err != nil {
+		errs = append(errs, fmt.Errorf("invalid expression: %w", err))
+	}
+	// Depending on the version of the Prometheus libraries, rulefmt may accept
+	// UTF-8 names, but most Prometheus servers still require legacy names.
+	if req.Record != "" && !model.IsValidLegacyMetricName(req.Record) {
+		errs = append(errs, fmt.Errorf("invalid recording rule name: %s", req.Record))
+	}
+	for ln := range req.Labels {
+		if !model.LegacyValidation.IsValidLabelName(ln) {
+			errs = append(errs, fmt.Errorf("invalid label name: %s", ln))
+		}
+	}
+	// Prometheus accepts any alert name, but since it becomes the value of
+	// the "alertname" label, it has to be a valid label value.
+	if req.Alert != "" && !model.LabelValue(req.Alert).IsValid() {
+		errs = append(errs, fmt.Errorf("invalid alerting rule name: %s", req.Alert))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	group := req.Group
+	if group == "" {
+		group = req.Record + req.Alert
+	}
+	out, err := yaml.Marshal(ruleGroupsFile{
+		Groups: []ruleGroup{{
+			Name:     group,
+			Interval: req.Interval,
+			Rules: []rulefmt.Rule{{
+				Record:        req.Record,
+				Alert:         req.Alert,
+				Expr:          strings.TrimSpace(req.Expr),
+				For:           req.For,
+				KeepFiringFor: req.KeepFiringFor,
+				Labels:        req.Labels,
+				Annotations:   req.Annotations,
+			}},
+		}},
+	})
+	if err != nil {
+		return nil, []error{fmt.Errorf("error marshaling rule group: %w", err)}
+	}
+
+	if _, errs := rulefmt.Parse(out); len(errs) > 0 {
+		for i, err := range errs {
+			// Strip the rule group / rule prefix and the positions within the
+			// generated YAML, which the user has never seen.
+			var rerr *rulefmt.Error
+			if errors.As(err, &rerr) {
+				errs[i] = rerr.Err.Unwrap()
+			}
+		}
+		return nil, errs
+	}
+	return out, nil
+}
+
+// HandleRuleExport turns an expression along with a rule name, labels,
+// annotations, and "for" duration from the JSON request body into a Prometheus
+// rule group YAML snippet. Invalid rules are rejected with a list of all
+// validation errors.
+func HandleRuleExport(features Features) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prom_httputil.SetCORS(w, CORSOrigin, r)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid HTTP method, use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		reqFeatures, err := ParseFeatures(r.URL.Query()["enable_feature"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var req ruleExportRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRuleBodySize)).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Error unmarshaling rule: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		out, errs := exportRule(req, features.Merge(reqFeatures))
+		if len(errs) > 0 {
+			msgs := make([]string, 0, len(errs))
+			for _, err := range errs {
+				msgs = append(msgs, err.Error())
+			}
+			http.Error(w, "Invalid rule:\n"+strings.Join(msgs, "\n"), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(out)
+	}
+}
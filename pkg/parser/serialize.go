@@ -0,0 +1,403 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	prom_httputil "github.com/prometheus/prometheus/util/httputil"
+)
+
+// maxASTBodySize is the maximum size of a JSON AST request body.
+const maxASTBodySize = 1024 * 1024
+
+// itemTypes maps the string representations of operators, aggregators, and
+// @ modifier preprocessors (as emitted by translateAST) back to their item types.
+var itemTypes = func() map[string]parser.ItemType {
+	m := map[string]parser.ItemType{}
+	for ty, s := range parser.ItemTypeStr {
+		if ty.IsOperator() || ty.IsAggregator() || ty == parser.START || ty == parser.END {
+			m[s] = ty
+		}
+	}
+	return m
+}()
+
+// jsonNode is the JSON representation of an AST node, as emitted by translateAST.
+type jsonNode struct {
+	Type       string        `json:"type"`
+	Op         string        `json:"op"`
+	Expr       *jsonNode     `json:"expr"`
+	Param      *jsonNode     `json:"param"`
+	Grouping   []string      `json:"grouping"`
+	Without    bool          `json:"without"`
+	LHS        *jsonNode     `json:"lhs"`
+	RHS        *jsonNode     `json:"rhs"`
+	Matching   *jsonMatching `json:"matching"`
+	Bool       bool          `json:"bool"`
+	Func       *jsonFunc     `json:"func"`
+	Args       []*jsonNode   `json:"args"`
+	Name       string        `json:"name"`
+	Range      int64         `json:"range"`
+	Offset     int64         `json:"offset"`
+	Step       int64         `json:"step"`
+	Matchers   []jsonMatcher `json:"matchers"`
+	Timestamp  *int64        `json:"timestamp"`
+	StartOrEnd *string       `json:"startOrEnd"`
+	Val        string        `json:"val"`
+}
+
+type jsonMatching struct {
+	Card    string   `json:"card"`
+	Labels  []string `json:"labels"`
+	On      bool     `json:"on"`
+	Include []string `json:"include"`
+}
+
+type jsonFunc struct {
+	Name string `json:"name"`
+}
+
+type jsonMatcher struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+func lookupOp(op string, valid func(parser.ItemType) bool) (parser.ItemType, error) {
+	ty, ok := itemTypes[op]
+	if !ok || !valid(ty) {
+		return 0, fmt.Errorf("invalid operator %q", op)
+	}
+	return ty, nil
+}
+
+func parseStartOrEnd(s *string) (parser.ItemType, error) {
+	if s == nil {
+		return 0, nil
+	}
+	switch ty := itemTypes[*s]; ty {
+	case parser.START, parser.END:
+		return ty, nil
+	}
+	return 0, fmt.Errorf("invalid @ modifier %q", *s)
+}
+
+func parseCard(card string) (parser.VectorMatchCardinality, error) {
+	for _, c := range []parser.VectorMatchCardinality{parser.CardOneToOne, parser.CardManyToOne, parser.CardOneToMany, parser.CardManyToMany} {
+		if c.String() == card {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid vector matching cardinality %q", card)
+}
+
+func parseMatchers(in []jsonMatcher) ([]*labels.Matcher, error) {
+	out := make([]*labels.Matcher, 0, len(in))
+	for _, m := range in {
+		var mt labels.MatchType
+		switch m.Type {
+		case labels.MatchEqual.String():
+			mt = labels.MatchEqual
+		case labels.MatchNotEqual.String():
+			mt = labels.MatchNotEqual
+		case labels.MatchRegexp.String():
+			mt = labels.MatchRegexp
+		case labels.MatchNotRegexp.String():
+			mt = labels.MatchNotRegexp
+		default:
+			return nil, fmt.Errorf("invalid matcher type %q", m.Type)
+		}
+		lm, err := labels.NewMatcher(mt, m.Name, m.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %s%s%q: %w", m.Name, m.Type, m.Value, err)
+		}
+		out = append(out, lm)
+	}
+	return out, nil
+}
+
+func msToDuration(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+func vectorSelector(n *jsonNode) (*parser.VectorSelector, error) {
+	matchers, err := parseMatchers(n.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	startOrEnd, err := parseStartOrEnd(n.StartOrEnd)
+	if err != nil {
+		return nil, err
+	}
+	return &parser.VectorSelector{
+		Name:           n.Name,
+		OriginalOffset: msToDuration(n.Offset),
+		LabelMatchers:  matchers,
+		Timestamp:      n.Timestamp,
+		StartOrEnd:     startOrEnd,
+	}, nil
+}
+
+// buildAST is the inverse of translateAST: it converts the JSON representation
+// of an AST back into a PromQL AST. The result is not type-checked.
+func buildAST(n *jsonNode) (parser.Expr, error) {
+	if n == nil {
+		return nil, errors.New("missing node")
+	}
+
+	switch n.Type {
+	case "aggregation":
+		op, err := lookupOp(n.Op, parser.ItemType.IsAggregator)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := buildAST(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		var param parser.Expr
+		if n.Param != nil {
+			if param, err = buildAST(n.Param); err != nil {
+				return nil, err
+			}
+		}
+		return &parser.AggregateExpr{
+			Op:       op,
+			Expr:     expr,
+			Param:    param,
+			Grouping: n.Grouping,
+			Without:  n.Without,
+		}, nil
+	case "binaryExpr":
+		op, err := lookupOp(n.Op, parser.ItemType.IsOperator)
+		if err != nil {
+			return nil, err
+		}
+		lhs, err := buildAST(n.LHS)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := buildAST(n.RHS)
+		if err != nil {
+			return nil, err
+		}
+		var matching *parser.VectorMatching
+		if m := n.Matching; m != nil {
+			card, err := parseCard(m.Card)
+			if err != nil {
+				return nil, err
+			}
+			matching = &parser.VectorMatching{
+				Card:           card,
+				MatchingLabels: m.Labels,
+				On:             m.On,
+				Include:        m.Include,
+			}
+		}
+		return &parser.BinaryExpr{
+			Op:             op,
+			LHS:            lhs,
+			RHS:            rhs,
+			VectorMatching: matching,
+			ReturnBool:     n.Bool,
+		}, nil
+	case "call":
+		if n.Func == nil {
+			return nil, errors.New("missing function in call")
+		}
+		fn, ok := parser.Functions[n.Func.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q", n.Func.Name)
+		}
+		args := make(parser.Expressions, 0, len(n.Args))
+		for _, a := range n.Args {
+			arg, err := buildAST(a)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return &parser.Call{
+			Func: fn,
+			Args: args,
+		}, nil
+	case "matrixSelector":
+		vs, err := vectorSelector(n)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.MatrixSelector{
+			VectorSelector: vs,
+			Range:          msToDuration(n.Range),
+		}, nil
+	case "subquery":
+		expr, err := buildAST(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		startOrEnd, err := parseStartOrEnd(n.StartOrEnd)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.SubqueryExpr{
+			Expr:           expr,
+			Range:          msToDuration(n.Range),
+			OriginalOffset: msToDuration(n.Offset),
+			Step:           msToDuration(n.Step),
+			Timestamp:      n.Timestamp,
+			StartOrEnd:     startOrEnd,
+		}, nil
+	case "numberLiteral":
+		val, err := strconv.ParseFloat(n.Val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number literal %q: %w", n.Val, err)
+		}
+		return &parser.NumberLiteral{Val: val}, nil
+	case "parenExpr":
+		expr, err := buildAST(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.ParenExpr{Expr: expr}, nil
+	case "stringLiteral":
+		return &parser.StringLiteral{Val: n.Val}, nil
+	case "unaryExpr":
+		op, err := lookupOp(n.Op, func(ty parser.ItemType) bool { return ty == parser.ADD || ty == parser.SUB })
+		if err != nil {
+			return nil, err
+		}
+		expr, err := buildAST(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.UnaryExpr{Op: op, Expr: expr}, nil
+	case "vectorSelector":
+		return vectorSelector(n)
+	}
+	return nil, fmt.Errorf("unsupported node type %q", n.Type)
+}
+
+// normalize replaces unary expressions over number literals (like "-1") with
+// the negated number literal, like the parser does, and sorts the label
+// matchers of selectors, whose order doesn't survive serialization.
+func normalize(expr parser.Expr) parser.Expr {
+	switch n := expr.(type) {
+	case *parser.AggregateExpr:
+		n.Expr = normalize(n.Expr)
+		if n.Param != nil {
+			n.Param = normalize(n.Param)
+		}
+	case *parser.BinaryExpr:
+		n.LHS = normalize(n.LHS)
+		n.RHS = normalize(n.RHS)
+	case *parser.Call:
+		for i, a := range n.Args {
+			n.Args[i] = normalize(a)
+		}
+	case *parser.ParenExpr:
+		n.Expr = normalize(n.Expr)
+	case *parser.SubqueryExpr:
+		n.Expr = normalize(n.Expr)
+	case *parser.MatrixSelector:
+		normalize(n.VectorSelector)
+	case *parser.VectorSelector:
+		sort.Slice(n.LabelMatchers, func(i, j int) bool {
+			return n.LabelMatchers[i].String() < n.LabelMatchers[j].String()
+		})
+	case *parser.UnaryExpr:
+		n.Expr = normalize(n.Expr)
+		if nl, ok := n.Expr.(*parser.NumberLiteral); ok {
+			if n.Op == parser.SUB {
+				nl.Val = -nl.Val
+			}
+			return nl
+		}
+	}
+	return expr
+}
+
+// serialize converts a JSON AST into a PromQL expression string and verifies
+// that parsing the string again yields the same AST.
+func serialize(n *jsonNode, features Features) (string, error) {
+	expr, err := buildAST(n)
+	if err != nil {
+		return "", err
+	}
+	expr = normalize(expr)
+	s := expr.String()
+
+	reparsed, err := ParseExpr(s, features)
+	if err != nil {
+		return "", fmt.Errorf("serialized expression %q is invalid: %w", s, err)
+	}
+	reparsed = normalize(reparsed)
+
+	want, err := json.Marshal(translateAST(expr))
+	if err != nil {
+		return "", fmt.Errorf("error marshaling AST: %w", err)
+	}
+	got, err := json.Marshal(translateAST(reparsed))
+	if err != nil {
+		return "", fmt.Errorf("error marshaling AST: %w", err)
+	}
+	if !bytes.Equal(want, got) {
+		return "", fmt.Errorf("serialized expression %q does not parse back into the provided AST", s)
+	}
+	return s, nil
+}
+
+// HandleSerialize converts a JSON AST (in the format returned by the parse
+// endpoints) from the request body back into a PromQL expression string.
+func HandleSerialize(features Features) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prom_httputil.SetCORS(w, CORSOrigin, r)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Invalid HTTP method, use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		reqFeatures, err := ParseFeatures(r.URL.Query()["enable_feature"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var node jsonNode
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxASTBodySize)).Decode(&node); err != nil {
+			http.Error(w, fmt.Sprintf("Error unmarshaling AST: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		s, err := serialize(&node, features.Merge(reqFeatures))
+		if err != nil {
+			writeError(w, map[string]string{"type": "error", "message": fmt.Sprintf("Error serializing AST: %v", err)})
+			return
+		}
+		writeJSON(w, map[string]string{"expr": s})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	for _, input := range []string{
+		`up`,
+		`up{job="api",instance!~"10\\..*"} offset 5m`,
+		`rate(http_requests_total[5m] @ end())`,
+		`sum without (instance) (rate(x[1m])) / on (job) group_left (team) y`,
+		`topk(5, x) > bool 2`,
+		`max_over_time(deriv(x[5m])[1h:1m] offset 1d)`,
+		`-x`,
+		`-1`,
+		`x * -2`,
+		`(1 + 2) * 3`,
+		`label_replace(x, "a", "$1", "b", "(.*)")`,
+		`x and (y or z)`,
+	} {
+		t.Run(input, func(t *testing.T) {
+			expr, err := ParseExpr(input, Features{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, err := json.Marshal(translateAST(expr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var n jsonNode
+			if err := json.Unmarshal(buf, &n); err != nil {
+				t.Fatal(err)
+			}
+			got, err := serialize(&n, Features{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != expr.String() {
+				t.Errorf("got %q, want %q", got, expr.String())
+			}
+		})
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	num := func(v string) *jsonNode { return &jsonNode{Type: "numberLiteral", Val: v} }
+	vs := func(name string) *jsonNode {
+		return &jsonNode{Type: "vectorSelector", Name: name, Matchers: []jsonMatcher{{Name: "__name__", Value: name, Type: "="}}}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		node    *jsonNode
+		want    string
+		wantErr string
+	}{
+		{
+			name: "negated number",
+			node: &jsonNode{Type: "unaryExpr", Op: "-", Expr: num("1")},
+			want: "-1",
+		},
+		{
+			name: "doubly negated number",
+			node: &jsonNode{Type: "unaryExpr", Op: "-", Expr: &jsonNode{Type: "unaryExpr", Op: "-", Expr: num("1")}},
+			want: "1",
+		},
+		{
+			name: "negated number in binary expression",
+			node: &jsonNode{Type: "binaryExpr", Op: "*", LHS: vs("x"), RHS: &jsonNode{Type: "unaryExpr", Op: "-", Expr: num("2.5")}},
+			want: "x * -2.5",
+		},
+		{
+			name: "negated selector",
+			node: &jsonNode{Type: "unaryExpr", Op: "-", Expr: vs("x")},
+			want: "-x",
+		},
+		{
+			name:    "missing parentheses",
+			node:    &jsonNode{Type: "binaryExpr", Op: "*", LHS: &jsonNode{Type: "binaryExpr", Op: "+", LHS: num("1"), RHS: num("2")}, RHS: num("3")},
+			wantErr: "does not parse back into the provided AST",
+		},
+		{
+			name:    "type error",
+			node:    &jsonNode{Type: "call", Func: &jsonFunc{Name: "rate"}, Args: []*jsonNode{vs("x")}},
+			wantErr: "is invalid",
+		},
+		{
+			name:    "invalid operator",
+			node:    &jsonNode{Type: "binaryExpr", Op: "sum", LHS: num("1"), RHS: num("2")},
+			wantErr: `invalid operator "sum"`,
+		},
+		{
+			name:    "unknown function",
+			node:    &jsonNode{Type: "call", Func: &jsonFunc{Name: "foo"}},
+			wantErr: `unknown function "foo"`,
+		},
+		{
+			name:    "invalid matcher",
+			node:    &jsonNode{Type: "vectorSelector", Matchers: []jsonMatcher{{Name: "job", Value: "(", Type: "=~"}}},
+			wantErr: "invalid matcher",
+		},
+		{
+			name:    "missing node",
+			node:    &jsonNode{Type: "parenExpr"},
+			wantErr: "missing node",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := serialize(tc.node, Features{})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v (%q)", tc.wantErr, err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandleSerialize(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		body   string
+		status int
+		want   string
+	}{
+		{method: http.MethodPost, body: `{"type":"numberLiteral","val":"1"}`, status: http.StatusOK, want: `{"expr":"1"}`},
+		{method: http.MethodPost, body: `{"type":"foo"}`, status: http.StatusBadRequest, want: `"type":"error"`},
+		{method: http.MethodPost, body: `{`, status: http.StatusBadRequest, want: "Error unmarshaling AST"},
+		{method: http.MethodGet, status: http.StatusMethodNotAllowed},
+	} {
+		rec := httptest.NewRecorder()
+		HandleSerialize(Features{})(rec, httptest.NewRequest(tc.method, "/api/serialize", strings.NewReader(tc.body)))
+		if rec.Code != tc.status || !strings.Contains(rec.Body.String(), tc.want) {
+			t.Errorf("%s %s: got status %d and body %q, want status %d and body containing %q", tc.method, tc.body, rec.Code, rec.Body.String(), tc.status, tc.want)
+		}
+	}
+}
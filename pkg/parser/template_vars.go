@@ -0,0 +1,384 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/promql/parser/posrange"
+)
+
+// placeholderDurationBase is the first duration (in milliseconds) or number
+// used to stand in for template variables in duration or scalar positions. It
+// is unlikely to collide with any duration or number that occurs in a real
+// query (~31 years).
+const placeholderDurationBase = 987654321000
+
+// templateVars replaces Grafana template variables (like "$job", "${job}",
+// "${job:regex}", "[[job]]", or "$__rate_interval") in an expression with
+// either user-provided values or placeholders, so that the expression can be
+// parsed. Placeholders can be mapped back to the original variables in the
+// translated AST afterwards.
+//
+// Variables within string literals are kept as they are, since they don't
+// prevent the expression from being parsed.
+//
+// Variables in expression positions are replaced by identifiers, which parse
+// as vector selectors. Where the parser expects a scalar (like in
+// "topk($n, x)") or where a variable is the operand of an arithmetic or
+// comparison operator (like in "x > $threshold"), they are replaced by a
+// number instead.
+type templateVars struct {
+	values map[string]string
+
+	// Placeholders by original variable text, and vice versa. Durations and
+	// numbers share their placeholder values.
+	durations       map[string]int64
+	idents          map[string]string
+	durationsRev    map[int64]string
+	identsRev       map[string]string
+	numPlaceholders int
+
+	// scalars records for the input offset of a variable in an expression
+	// position whether it is replaced by a number (true) or an identifier
+	// (false). Variables that are missing are replaced by an identifier, but
+	// may still become numbers.
+	scalars map[int]bool
+	// exprVars are the variables in expression positions of the last
+	// replacement.
+	exprVars []exprVar
+}
+
+// exprVar is a variable in an expression position.
+type exprVar struct {
+	// offset is the position of the variable in the input.
+	offset int
+	// pos is the position of its placeholder in the replaced input.
+	pos posrange.PositionRange
+}
+
+func newTemplateVars(values map[string]string) *templateVars {
+	return &templateVars{
+		values:       values,
+		durations:    map[string]int64{},
+		idents:       map[string]string{},
+		durationsRev: map[int64]string{},
+		identsRev:    map[string]string{},
+		scalars:      map[int]bool{},
+	}
+}
+
+func isVarNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// scanVar returns the full text and the name of a template variable at the
+// beginning of s, or empty strings if s doesn't start with a variable.
+func scanVar(s string) (text, name string) {
+	var inner string
+	switch {
+	case strings.HasPrefix(s, "${"):
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", ""
+		}
+		text, inner = s[:end+1], s[2:end]
+	case strings.HasPrefix(s, "[["):
+		end := strings.Index(s, "]]")
+		if end < 0 {
+			return "", ""
+		}
+		text, inner = s[:end+2], s[2:end]
+	case strings.HasPrefix(s, "$"):
+		end := 1
+		for end < len(s) && isVarNameChar(s[end]) {
+			end++
+		}
+		if end == 1 {
+			return "", ""
+		}
+		return s[:end], s[1:end]
+	default:
+		return "", ""
+	}
+
+	// Strip format options like in "${job:regex}".
+	name, _, _ = strings.Cut(inner, ":")
+	if name == "" || strings.IndexFunc(name, func(r rune) bool { return r > 127 || !isVarNameChar(byte(r)) }) >= 0 {
+		return "", ""
+	}
+	return text, name
+}
+
+// inDurationPosition returns whether a template variable that follows the
+// given (already processed) expression text would be in a position where
+// PromQL expects a duration: a range, a subquery step, or an offset.
+func inDurationPosition(before string, bracketDepth int) bool {
+	before = strings.TrimRight(before, " \t\r\n")
+	switch {
+	case strings.HasSuffix(before, "["):
+		return true
+	case strings.HasSuffix(before, ":") && bracketDepth > 0:
+		return true
+	}
+	lower := strings.ToLower(before)
+	return strings.HasSuffix(lower, "offset") &&
+		(len(lower) == len("offset") || !isVarNameChar(lower[len(lower)-len("offset")-1]))
+}
+
+func (tv *templateVars) placeholder(text string, duration, scalar bool) string {
+	if duration || scalar {
+		ms, ok := tv.durations[text]
+		if !ok {
+			ms = placeholderDurationBase + int64(tv.numPlaceholders)
+			tv.numPlaceholders++
+			tv.durations[text] = ms
+			tv.durationsRev[ms] = text
+		}
+		if scalar {
+			return strconv.FormatInt(ms, 10)
+		}
+		return fmt.Sprintf("%dms", ms)
+	}
+
+	ident, ok := tv.idents[text]
+	if !ok {
+		ident = fmt.Sprintf("__grafana_var_%d__", tv.numPlaceholders)
+		tv.numPlaceholders++
+		tv.idents[text] = ident
+		tv.identsRev[ident] = text
+	}
+	return ident
+}
+
+// replace replaces all template variables outside of string literals and
+// comments in the input.
+func (tv *templateVars) replace(input string) string {
+	var (
+		out          strings.Builder
+		quote        byte
+		bracketDepth int
+	)
+	tv.exprVars = nil
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+
+		switch {
+		case quote != 0:
+			out.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(input) {
+				i++
+				out.WriteByte(input[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '#':
+			end := strings.IndexByte(input[i:], '\n')
+			if end < 0 {
+				end = len(input) - i
+			}
+			out.WriteString(input[i : i+end])
+			i += end - 1
+			continue
+		case c == '$' || strings.HasPrefix(input[i:], "[["):
+			if text, name := scanVar(input[i:]); text != "" {
+				if v, ok := tv.values[name]; ok {
+					out.WriteString(v)
+				} else if inDurationPosition(out.String(), bracketDepth) {
+					out.WriteString(tv.placeholder(text, true, false))
+				} else {
+					start := out.Len()
+					out.WriteString(tv.placeholder(text, false, tv.scalars[i]))
+					tv.exprVars = append(tv.exprVars, exprVar{
+						offset: i,
+						pos:    posrange.PositionRange{Start: posrange.Pos(start), End: posrange.Pos(out.Len())},
+					})
+				}
+				i += len(text) - 1
+				continue
+			}
+			if c == '[' {
+				bracketDepth++
+			}
+		case c == '[':
+			bracketDepth++
+		case c == ']':
+			bracketDepth--
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// parse replaces the template variables in the input and parses it. As long
+// as the parser rejects a variable placeholder because it expects a scalar,
+// or a placeholder is the operand of an operator that takes scalars, the
+// variable is replaced by a number and the input is parsed again.
+func (tv *templateVars) parse(input string, features Features) (parser.Expr, error) {
+	expr, err := ParseExpr(tv.replace(input), features)
+	for {
+		var (
+			offset int
+			ok     bool
+		)
+		if err != nil {
+			offset, ok = tv.scalarOnTypeError(err)
+		} else {
+			offset, ok = tv.scalarOperand(expr)
+		}
+		if !ok {
+			return expr, err
+		}
+
+		tv.scalars[offset] = true
+		next, nextErr := ParseExpr(tv.replace(input), features)
+		if err == nil && nextErr != nil {
+			// The variable can't be a number here, so keep the selector.
+			tv.scalars[offset] = false
+			tv.replace(input)
+			continue
+		}
+		expr, err = next, nextErr
+	}
+}
+
+// exprVarAt returns the input offset of the not yet decided variable whose
+// placeholder spans exactly the given position range.
+func (tv *templateVars) exprVarAt(pos posrange.PositionRange) (int, bool) {
+	for _, v := range tv.exprVars {
+		if _, decided := tv.scalars[v.offset]; v.pos == pos && !decided {
+			return v.offset, true
+		}
+	}
+	return 0, false
+}
+
+// scalarOnTypeError returns the input offset of a variable that the parser
+// rejected because it expects a scalar in its place.
+func (tv *templateVars) scalarOnTypeError(err error) (int, bool) {
+	var errs parser.ParseErrors
+	if !errors.As(err, &errs) {
+		return 0, false
+	}
+	for _, e := range errs {
+		if e.Err != nil && strings.HasPrefix(e.Err.Error(), "expected type scalar") {
+			if offset, ok := tv.exprVarAt(e.PositionRange); ok {
+				return offset, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// scalarOperand returns the input offset of a variable that was parsed as a
+// plain vector selector, but is an operand of an arithmetic or comparison
+// operator, where Grafana variables almost always stand for numbers.
+func (tv *templateVars) scalarOperand(expr parser.Expr) (int, bool) {
+	var (
+		offset int
+		found  bool
+	)
+	parser.Inspect(expr, func(node parser.Node, path []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok || found || len(vs.LabelMatchers) != 1 || vs.OriginalOffset != 0 || vs.Timestamp != nil || vs.StartOrEnd != 0 {
+			return nil
+		}
+		if _, ok := tv.identsRev[vs.Name]; !ok {
+			return nil
+		}
+		i := len(path) - 1
+		for i >= 0 {
+			if _, ok := path[i].(*parser.ParenExpr); !ok {
+				break
+			}
+			i--
+		}
+		if i < 0 {
+			return nil
+		}
+		if be, ok := path[i].(*parser.BinaryExpr); ok && !be.Op.IsSetOperator() {
+			offset, found = tv.exprVarAt(vs.PositionRange())
+		}
+		return nil
+	})
+	return offset, found
+}
+
+// restoreString replaces all placeholders in a string (such as an error
+// message) with the original template variables.
+func (tv *templateVars) restoreString(s string) string {
+	for ident, text := range tv.identsRev {
+		s = strings.ReplaceAll(s, ident, text)
+	}
+	for ms, text := range tv.durationsRev {
+		s = strings.ReplaceAll(s, fmt.Sprintf("%dms", ms), text)
+		s = strings.ReplaceAll(s, strconv.FormatInt(ms, 10), text)
+	}
+	return s
+}
+
+// restore maps placeholders in a translated AST back to the original template
+// variables. Placeholder identifiers (in metric names, label names, and
+// grouping labels), placeholder durations (in the "range", "offset", and "step"
+// fields), and placeholder numbers are replaced with the original variable
+// text.
+func (tv *templateVars) restore(node interface{}) {
+	switch n := node.(type) {
+	case map[string]string:
+		if n["type"] == "numberLiteral" {
+			if ms, err := strconv.ParseInt(n["val"], 10, 64); err == nil {
+				if text, ok := tv.durationsRev[ms]; ok {
+					n["val"] = text
+				}
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range n {
+			switch val := v.(type) {
+			case string:
+				if text, ok := tv.identsRev[val]; ok {
+					n[k] = text
+				}
+			case int64:
+				if text, ok := tv.durationsRev[val]; ok && (k == "range" || k == "offset" || k == "step") {
+					n[k] = text
+				}
+			case []string:
+				for i, s := range val {
+					if text, ok := tv.identsRev[s]; ok {
+						val[i] = text
+					}
+				}
+			default:
+				tv.restore(v)
+			}
+		}
+	case []interface{}:
+		for _, c := range n {
+			tv.restore(c)
+		}
+	case []map[string]interface{}:
+		for _, c := range n {
+			tv.restore(c)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestScanVar(t *testing.T) {
+	for _, tc := range []struct {
+		input, text, name string
+	}{
+		{input: "$job", text: "$job", name: "job"},
+		{input: "$__rate_interval])", text: "$__rate_interval", name: "__rate_interval"},
+		{input: "${job}", text: "${job}", name: "job"},
+		{input: "${job:regex}", text: "${job:regex}", name: "job"},
+		{input: "[[instance]]", text: "[[instance]]", name: "instance"},
+		{input: "$"},
+		{input: "${job"},
+		{input: "${jöb}"},
+		{input: "[5m]"},
+	} {
+		text, name := scanVar(tc.input)
+		if text != tc.text || name != tc.name {
+			t.Errorf("scanVar(%q) = (%q, %q), want (%q, %q)", tc.input, text, name, tc.text, tc.name)
+		}
+	}
+}
+
+func TestParseTemplateVars(t *testing.T) {
+	for _, tc := range []struct {
+		input  string
+		values map[string]string
+		// want are JSON fragments that the AST must contain.
+		want    []string
+		wantErr string
+	}{
+		{
+			input: `rate(http_requests_total{job=~"$job"}[$__rate_interval])`,
+			want:  []string{`"range":"$__rate_interval"`, `"value":"$job"`},
+		},
+		{
+			input: `sum by ($label) (x offset ${offset}) / max_over_time(x[1h:$step])`,
+			want:  []string{`"grouping":["$label"]`, `"offset":"${offset}"`, `"step":"$step"`, `"range":3600000`},
+		},
+		{
+			input:  `rate(x[$__rate_interval])`,
+			values: map[string]string{"__rate_interval": "5m"},
+			want:   []string{`"range":300000`},
+		},
+		{
+			input: `rate([[metric]][5m])`,
+			want:  []string{`"name":"[[metric]]"`},
+		},
+		// Variables where the parser expects a scalar.
+		{
+			input: `histogram_quantile($q, sum by (le) (rate(x_bucket[5m])))`,
+			want:  []string{`{"type":"numberLiteral","val":"$q"}`},
+		},
+		{
+			input: `topk($n, x)`,
+			want:  []string{`"param":{"type":"numberLiteral","val":"$n"}`},
+		},
+		// Variables as operands of arithmetic and comparison operators.
+		{
+			input: `x > $threshold`,
+			want:  []string{`"rhs":{"type":"numberLiteral","val":"$threshold"}`},
+		},
+		{
+			input: `rate(x[$__interval]) * ($__interval_ms / 1000)`,
+			want:  []string{`"range":"$__interval"`, `"lhs":{"type":"numberLiteral","val":"$__interval_ms"}`},
+		},
+		{
+			input: `$a + $b`,
+			want:  []string{`"lhs":{"type":"numberLiteral","val":"$a"}`, `"rhs":{"type":"numberLiteral","val":"$b"}`},
+		},
+		// Set operators need vectors, so variables stay selectors.
+		{
+			input: `x and $metric`,
+			want:  []string{`"rhs":{"matchers":[{"name":"__name__","type":"=","value":"$metric"}],"name":"$metric"`},
+		},
+		{
+			input: `x > $metric{job="a"}`,
+			want:  []string{`"name":"$metric"`},
+		},
+		{
+			input:   `x offset $offset $foo`,
+			wantErr: `unexpected identifier "$foo"`,
+		},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			opts := parseOptions{templateVars: true, templateVarValues: tc.values}
+			ast, err := opts.parse(tc.input)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, err := json.Marshal(ast)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(string(buf), w) {
+					t.Errorf("AST doesn't contain %s:\n%s", w, buf)
+				}
+			}
+			if strings.Contains(string(buf), "987654321") || strings.Contains(string(buf), "__grafana_var_") {
+				t.Errorf("AST contains placeholders:\n%s", buf)
+			}
+		})
+	}
+}
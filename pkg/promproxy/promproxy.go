@@ -0,0 +1,270 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promproxy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+
+	"github.com/grafana/regexp"
+	"github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/config"
+
+	"github.com/prometheus/promlens/pkg/audit"
+)
+
+// pathPrefix is the path below which Prometheus servers are proxied.
+const pathPrefix = "/api/prometheus/"
+
+// allowedPaths are the read-only Prometheus API paths that may be proxied.
+var allowedPaths = regexp.MustCompile(`^/api/v1/(?:` + strings.Join([]string{
+	`query`,
+	`query_range`,
+	`query_exemplars`,
+	`format_query`,
+	`series`,
+	`labels`,
+	`label/[^/]+/values`,
+	`metadata`,
+	`status/buildinfo`,
+}, "|") + `)$`)
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ErrUnknownServer is returned when a Prometheus server name is not configured.
+var ErrUnknownServer = errors.New("unknown Prometheus server")
+
+// Access modes of a Prometheus server.
+const (
+	// AccessProxy makes the browser query the server through the proxy.
+	AccessProxy = "proxy"
+	// AccessDirect makes the browser query the server directly.
+	AccessDirect = "direct"
+)
+
+// ServerConfig configures a single Prometheus server to proxy to.
+type ServerConfig struct {
+	// Name identifies the server in the proxy path.
+	Name        string      `yaml:"name"`
+	URL         *config.URL `yaml:"url"`
+	Description string      `yaml:"description,omitempty"`
+	// Access is either AccessProxy or AccessDirect. Servers with direct access
+	// are only offered for selection in the UI, but not proxied.
+	Access string `yaml:"access,omitempty"`
+
+	HTTPClientConfig config.HTTPClientConfig `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *ServerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.HTTPClientConfig = config.DefaultHTTPClientConfig
+	c.Access = AccessProxy
+	type plain ServerConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if !validName.MatchString(c.Name) {
+		return fmt.Errorf("invalid Prometheus server name %q, must match %s", c.Name, validName)
+	}
+	if c.URL == nil {
+		return fmt.Errorf("missing URL for Prometheus server %q", c.Name)
+	}
+	if c.Access != AccessProxy && c.Access != AccessDirect {
+		return fmt.Errorf("invalid access mode %q for Prometheus server %q, must be %q or %q", c.Access, c.Name, AccessProxy, AccessDirect)
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+type server struct {
+	cfg ServerConfig
+	rt  http.RoundTripper
+	// proxy is nil for servers with direct access.
+	proxy *httputil.ReverseProxy
+}
+
+// Proxy proxies read-only Prometheus API requests to a set of configured
+// Prometheus servers, so that the browser doesn't have to reach them directly.
+type Proxy struct {
+	logger *slog.Logger
+
+	mtx     sync.RWMutex
+	servers map[string]*server
+}
+
+// New creates a new Proxy without any servers.
+func New(logger *slog.Logger) *Proxy {
+	return &Proxy{
+		logger:  logger,
+		servers: map[string]*server{},
+	}
+}
+
+// Servers is a validated set of Prometheus servers to proxy to. It is created
+// by NewServers and activated by SetServers, so that a configuration reload
+// can validate all of its parts before applying any of them.
+type Servers struct {
+	servers map[string]*server
+}
+
+// NewServers validates the server configurations and creates their proxies.
+func (p *Proxy) NewServers(cfgs []ServerConfig) (*Servers, error) {
+	servers := make(map[string]*server, len(cfgs))
+	for _, sc := range cfgs {
+		if _, ok := servers[sc.Name]; ok {
+			return nil, fmt.Errorf("duplicate Prometheus server name %q", sc.Name)
+		}
+		rt, err := config.NewRoundTripperFromConfig(sc.HTTPClientConfig, "promlens_"+sc.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error creating HTTP client for Prometheus server %q: %w", sc.Name, err)
+		}
+		s := &server{cfg: sc, rt: rt}
+		if sc.Access == AccessProxy {
+			s.proxy = newReverseProxy(p.logger, sc, rt)
+		}
+		servers[sc.Name] = s
+	}
+	return &Servers{servers: servers}, nil
+}
+
+// SetServers replaces the set of proxied servers and returns the previous
+// set, which the caller should close.
+func (p *Proxy) SetServers(s *Servers) *Servers {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	old := &Servers{servers: p.servers}
+	p.servers = s.servers
+	return old
+}
+
+// Close closes the idle connections of the servers' HTTP clients. Requests
+// that are still in progress are not interrupted, and their connections are
+// closed by the clients' idle timeout after they complete.
+func (s *Servers) Close() {
+	for _, srv := range s.servers {
+		if c, ok := srv.rt.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+	}
+}
+
+func newReverseProxy(logger *slog.Logger, sc ServerConfig, rt http.RoundTripper) *httputil.ReverseProxy {
+	target := sc.URL.URL
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			// Don't leak the user's PromLens credentials to Prometheus. The
+			// configured credentials are added by the round tripper.
+			r.Out.Header.Del("Authorization")
+			r.Out.Header.Del("Cookie")
+		},
+		Transport: rt,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Error("Error proxying to Prometheus", "server", sc.Name, "path", r.URL.Path, "err", err)
+			http.Error(w, fmt.Sprintf("Error proxying to Prometheus server %q", sc.Name), http.StatusBadGateway)
+		},
+	}
+}
+
+func (p *Proxy) server(name string) (*server, bool) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	s, ok := p.servers[name]
+	return s, ok
+}
+
+// PrometheusAPI returns a client for the Prometheus API of the server with the
+// given name, using the server's HTTP client configuration. Unlike Handle, it
+// also serves servers with direct access, which PromLens then has to be able
+// to reach itself.
+func (p *Proxy) PrometheusAPI(name string) (v1.API, error) {
+	s, ok := p.server(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownServer, name)
+	}
+	c, err := api.NewClient(api.Config{
+		Address:      s.cfg.URL.String(),
+		RoundTripper: s.rt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating Prometheus API client: %w", err)
+	}
+	return v1.NewAPI(c), nil
+}
+
+// splitPath splits a request path of the form "/api/prometheus/<name>/<path>"
+// into the server name and the Prometheus API path.
+func splitPath(path string) (name, apiPath string, err error) {
+	rest, ok := strings.CutPrefix(path, pathPrefix)
+	if !ok {
+		return "", "", errors.New("invalid proxy path")
+	}
+	name, apiPath, ok = strings.Cut(rest, "/")
+	if !ok {
+		return "", "", errors.New("missing Prometheus API path")
+	}
+	return name, "/" + apiPath, nil
+}
+
+// Path returns the path below the route prefix at which the server with the
+// given name is proxied.
+func Path(name string) string {
+	return pathPrefix + name
+}
+
+// Handle proxies requests to "<routePrefix>/api/prometheus/<name>/api/v1/..."
+// to the Prometheus server with the given name. Only read-only API paths are
+// allowed. Proxied requests are recorded in the audit log.
+func (p *Proxy) Handle(routePrefix string, al *audit.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, apiPath, err := splitPath(strings.TrimPrefix(r.URL.Path, routePrefix))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s, ok := p.server(name)
+		if !ok || s.proxy == nil {
+			http.Error(w, fmt.Sprintf("Unknown Prometheus server %q", name), http.StatusNotFound)
+			return
+		}
+		// The ServeMux already cleans the path, so "." and ".." elements can't
+		// be used to escape the allowlist.
+		if !allowedPaths.MatchString(apiPath) {
+			http.Error(w, fmt.Sprintf("Proxying to %q is not allowed", apiPath), http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "Invalid HTTP method, use GET or POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if al.Enabled() {
+			al.Log(r, audit.Event{
+				Action:           audit.ActionProxy,
+				PrometheusServer: name,
+				Path:             apiPath,
+				Query:            audit.Query(r),
+			})
+		}
+
+		r.URL.Path = apiPath
+		r.URL.RawPath = ""
+		s.proxy.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promproxy
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/config"
+
+	"github.com/prometheus/promlens/pkg/audit"
+)
+
+// backend is a fake Prometheus server that records the requests it receives
+// and the number of connections that were closed.
+type backend struct {
+	*httptest.Server
+
+	mtx         sync.Mutex
+	requests    []*http.Request
+	closedConns int
+}
+
+func newBackend(t *testing.T) *backend {
+	b := &backend{}
+	b.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.mtx.Lock()
+		b.requests = append(b.requests, r)
+		b.mtx.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	b.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			b.mtx.Lock()
+			b.closedConns++
+			b.mtx.Unlock()
+		}
+	}
+	b.Start()
+	t.Cleanup(b.Close)
+	return b
+}
+
+func (b *backend) closedConnections() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.closedConns
+}
+
+func (b *backend) lastRequest() *http.Request {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if len(b.requests) == 0 {
+		return nil
+	}
+	return b.requests[len(b.requests)-1]
+}
+
+func newTestProxy(t *testing.T, b *backend) *Proxy {
+	u, err := url.Parse(b.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withAuth := config.DefaultHTTPClientConfig
+	withAuth.BasicAuth = &config.BasicAuth{Username: "promlens", Password: "configured"}
+
+	p := New(slog.New(slog.DiscardHandler))
+	servers, err := p.NewServers([]ServerConfig{
+		{Name: "prom", URL: &config.URL{URL: u}, Access: AccessProxy, HTTPClientConfig: config.DefaultHTTPClientConfig},
+		{Name: "with-auth", URL: &config.URL{URL: u}, Access: AccessProxy, HTTPClientConfig: withAuth},
+		{Name: "direct", URL: &config.URL{URL: u}, Access: AccessDirect, HTTPClientConfig: config.DefaultHTTPClientConfig},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetServers(servers).Close()
+	t.Cleanup(func() { p.SetServers(&Servers{}).Close() })
+	return p
+}
+
+func TestHandle(t *testing.T) {
+	b := newBackend(t)
+	p := newTestProxy(t, b)
+
+	// Serve the proxy through a ServeMux, like the web package does.
+	mux := http.NewServeMux()
+	mux.Handle(pathPrefix, p.Handle("", audit.New()))
+
+	for _, tc := range []struct {
+		method   string
+		target   string
+		status   int
+		wantPath string
+	}{
+		{method: http.MethodGet, target: "/api/prometheus/prom/api/v1/query?query=up", status: http.StatusOK, wantPath: "/api/v1/query"},
+		{method: http.MethodPost, target: "/api/prometheus/prom/api/v1/query_range", status: http.StatusOK, wantPath: "/api/v1/query_range"},
+		{method: http.MethodGet, target: "/api/prometheus/prom/api/v1/label/job/values", status: http.StatusOK, wantPath: "/api/v1/label/job/values"},
+		{method: http.MethodGet, target: "/api/prometheus/prom/api/v1/status/buildinfo", status: http.StatusOK, wantPath: "/api/v1/status/buildinfo"},
+
+		// Unknown and non-proxied servers.
+		{method: http.MethodGet, target: "/api/prometheus/unknown/api/v1/query", status: http.StatusNotFound},
+		{method: http.MethodGet, target: "/api/prometheus/direct/api/v1/query", status: http.StatusNotFound},
+		{method: http.MethodGet, target: "/api/prometheus/prom", status: http.StatusBadRequest},
+
+		// Paths that are not on the read-only allowlist.
+		{method: http.MethodPost, target: "/api/prometheus/prom/api/v1/admin/tsdb/delete_series", status: http.StatusForbidden},
+		{method: http.MethodPost, target: "/api/prometheus/prom/api/v1/admin/tsdb/snapshot", status: http.StatusForbidden},
+		{method: http.MethodPost, target: "/api/prometheus/prom/-/quit", status: http.StatusForbidden},
+		{method: http.MethodPost, target: "/api/prometheus/prom/-/reload", status: http.StatusForbidden},
+		{method: http.MethodGet, target: "/api/prometheus/prom/api/v1/status/config", status: http.StatusForbidden},
+		{method: http.MethodGet, target: "/api/prometheus/prom/api/v1/query/", status: http.StatusForbidden},
+		{method: http.MethodGet, target: "/api/prometheus/prom/api/v1/label/job/values/extra", status: http.StatusForbidden},
+
+		// Encoded path traversal must not escape the allowlist.
+		{method: http.MethodPost, target: "/api/prometheus/prom/api/v1/query%2F..%2F..%2F..%2F-%2Fquit", status: http.StatusForbidden},
+		{method: http.MethodPost, target: "/api/prometheus/prom/api/v1/label/%2E%2E%2F%2E%2E%2Fadmin/values", status: http.StatusForbidden},
+		{method: http.MethodPost, target: "/api/prometheus/prom/api/v1/label/..%2F..%2Fadmin%2Ftsdb%2Fsnapshot%23/values", status: http.StatusForbidden},
+
+		// Only GET and POST are allowed.
+		{method: http.MethodPut, target: "/api/prometheus/prom/api/v1/query", status: http.StatusMethodNotAllowed},
+		{method: http.MethodDelete, target: "/api/prometheus/prom/api/v1/series", status: http.StatusMethodNotAllowed},
+	} {
+		before := b.lastRequest()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: got status %d, want %d: %s", tc.method, tc.target, rec.Code, tc.status, rec.Body.String())
+			continue
+		}
+		last := b.lastRequest()
+		if tc.status != http.StatusOK {
+			if last != before {
+				t.Errorf("%s %s: rejected request reached the backend as %s", tc.method, tc.target, last.URL)
+			}
+			continue
+		}
+		if last == before || last.URL.Path != tc.wantPath || last.Method != tc.method {
+			t.Errorf("%s %s: backend received unexpected request %+v", tc.method, tc.target, last)
+		}
+	}
+}
+
+func TestHandleCredentials(t *testing.T) {
+	b := newBackend(t)
+	p := newTestProxy(t, b)
+	h := p.Handle("", audit.New())
+
+	for _, tc := range []struct {
+		server   string
+		wantAuth string
+	}{
+		{server: "prom"},
+		{server: "with-auth", wantAuth: "Basic cHJvbWxlbnM6Y29uZmlndXJlZA=="},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/prometheus/"+tc.server+"/api/v1/query?query=up", nil)
+		req.Header.Set("Authorization", "Bearer promlens-user-token")
+		req.AddCookie(&http.Cookie{Name: "promlens_session", Value: "secret"})
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d: %s", tc.server, rec.Code, rec.Body.String())
+		}
+
+		last := b.lastRequest()
+		if got := last.Header.Get("Authorization"); got != tc.wantAuth {
+			t.Errorf("%s: backend received Authorization header %q, want %q", tc.server, got, tc.wantAuth)
+		}
+		if got := last.Header.Get("Cookie"); got != "" {
+			t.Errorf("%s: backend received client cookies %q", tc.server, got)
+		}
+	}
+}
+
+func TestServersClose(t *testing.T) {
+	b := newBackend(t)
+	p := newTestProxy(t, b)
+	h := p.Handle("", audit.New())
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/api/prometheus/prom/api/v1/query?query=up", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Replacing the servers closes the idle connections of the previous ones.
+	servers, err := p.NewServers(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := p.SetServers(servers)
+	if _, ok := old.servers["prom"]; !ok {
+		t.Fatalf("SetServers returned unexpected previous servers %v", old.servers)
+	}
+	if n := b.closedConnections(); n != 0 {
+		t.Fatalf("%d connections closed before closing the previous servers", n)
+	}
+	old.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for b.closedConnections() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle connection to the backend was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/api/prometheus/prom/api/v1/query?query=up", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d for removed server, want %d", rec.Code, http.StatusNotFound)
+	}
+}
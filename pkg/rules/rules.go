@@ -0,0 +1,205 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+var (
+	ruleFileLoads = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "promlens_rule_file_loads_total",
+		Help: "The total number of times the rule files were loaded.",
+	})
+	ruleFileLoadErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "promlens_rule_file_load_errors_total",
+		Help: "The total number of errors while loading the rule files.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(ruleFileLoads, ruleFileLoadErrors)
+}
+
+// Rule is a single alerting or recording rule.
+type Rule struct {
+	Record        string            `json:"record,omitempty"`
+	Alert         string            `json:"alert,omitempty"`
+	Expr          string            `json:"expr"`
+	For           model.Duration    `json:"for,omitempty"`
+	KeepFiringFor model.Duration    `json:"keepFiringFor,omitempty"`
+	Labels        map[string]string `json:"labels"`
+	Annotations   map[string]string `json:"annotations"`
+}
+
+// Group is a rule group from a rule file.
+type Group struct {
+	Name     string         `json:"name"`
+	File     string         `json:"file"`
+	Interval model.Duration `json:"interval,omitempty"`
+	Rules    []Rule         `json:"rules"`
+}
+
+// RecordingRule is a recording rule along with the group it belongs to.
+type RecordingRule struct {
+	Rule
+	Group string `json:"group"`
+	File  string `json:"file"`
+}
+
+// Manager loads Prometheus rule files matching a list of file globs.
+type Manager struct {
+	logger    *slog.Logger
+	patterns  []string
+	checkExpr func(expr string) error
+
+	mtx     sync.RWMutex
+	groups  []Group
+	records map[string][]RecordingRule
+}
+
+// NewManager creates a new rule manager and loads the rule files matching the
+// provided globs. Rule expressions are validated by rulefmt and additionally
+// by checkExpr (if set), since rulefmt accepts experimental PromQL functions
+// whenever any part of the process enables them.
+func NewManager(logger *slog.Logger, patterns []string, checkExpr func(expr string) error) (*Manager, error) {
+	m := &Manager{
+		logger:    logger,
+		patterns:  patterns,
+		checkExpr: checkExpr,
+		records:   map[string][]RecordingRule{},
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload reloads all rule files. If any file fails to load, the previously
+// loaded rules are kept.
+func (m *Manager) Reload() (err error) {
+	ruleFileLoads.Inc()
+	defer func() {
+		if err != nil {
+			ruleFileLoadErrors.Inc()
+		}
+	}()
+
+	var files []string
+	for _, pat := range m.patterns {
+		fs, err := filepath.Glob(pat)
+		if err != nil {
+			// The only error can be a bad pattern.
+			return fmt.Errorf("error retrieving rule files for %s: %w", pat, err)
+		}
+		files = append(files, fs...)
+	}
+
+	groups := []Group{}
+	records := map[string][]RecordingRule{}
+	for _, fn := range files {
+		rgs, errs := rulefmt.ParseFile(fn)
+		if len(errs) > 0 {
+			return fmt.Errorf("error loading rule file %q: %w", fn, errors.Join(errs...))
+		}
+
+		for _, rg := range rgs.Groups {
+			g := Group{
+				Name:     rg.Name,
+				File:     fn,
+				Interval: rg.Interval,
+				Rules:    make([]Rule, 0, len(rg.Rules)),
+			}
+			for _, rn := range rg.Rules {
+				if m.checkExpr != nil {
+					if err := m.checkExpr(rn.Expr.Value); err != nil {
+						return fmt.Errorf("error loading rule file %q: group %q, rule %q: %w", fn, rg.Name, rn.Record.Value+rn.Alert.Value, err)
+					}
+				}
+				r := Rule{
+					Record:        rn.Record.Value,
+					Alert:         rn.Alert.Value,
+					Expr:          rn.Expr.Value,
+					For:           rn.For,
+					KeepFiringFor: rn.KeepFiringFor,
+					Labels:        rn.Labels,
+					Annotations:   rn.Annotations,
+				}
+				if r.Labels == nil {
+					r.Labels = map[string]string{}
+				}
+				if r.Annotations == nil {
+					r.Annotations = map[string]string{}
+				}
+				g.Rules = append(g.Rules, r)
+
+				if r.Record != "" {
+					records[r.Record] = append(records[r.Record], RecordingRule{Rule: r, Group: g.Name, File: fn})
+				}
+			}
+			groups = append(groups, g)
+		}
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.groups = groups
+	m.records = records
+	m.logger.Info("Loaded rule files", "files", len(files), "groups", len(groups))
+	return nil
+}
+
+// Groups returns all loaded rule groups.
+func (m *Manager) Groups() []Group {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.groups
+}
+
+// RecordingRules returns all recording rules that record the given metric name.
+func (m *Manager) RecordingRules(metric string) []RecordingRule {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.records[metric]
+}
+
+// Handle responds with all loaded rule groups.
+func Handle(m *Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if m == nil {
+			http.Error(w, "No rule files configured.", http.StatusServiceUnavailable)
+			return
+		}
+
+		buf, err := json.Marshal(map[string]interface{}{
+			"groups": m.Groups(),
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error marshaling rule groups: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(buf)
+	}
+}
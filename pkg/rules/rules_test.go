@@ -0,0 +1,81 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const ruleFile = `
+groups:
+  - name: example
+    rules:
+      - record: job:http_requests:rate5m
+        expr: sum by (job) (rate(http_requests_total[5m]))
+      - alert: HighErrorRate
+        expr: job:http_requests:rate5m > 100
+        for: 5m
+`
+
+func TestManager(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rules.yml"), []byte(ruleFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(slog.New(slog.DiscardHandler), []string{filepath.Join(dir, "*.yml")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := m.Groups()
+	if len(groups) != 1 || len(groups[0].Rules) != 2 || groups[0].Rules[1].Alert != "HighErrorRate" {
+		t.Fatalf("unexpected rule groups %+v", groups)
+	}
+	rrs := m.RecordingRules("job:http_requests:rate5m")
+	if len(rrs) != 1 || rrs[0].Group != "example" || rrs[0].Labels == nil {
+		t.Errorf("unexpected recording rules %+v", rrs)
+	}
+
+	// A failed reload keeps the previously loaded rules.
+	if err := os.WriteFile(filepath.Join(dir, "broken.yml"), []byte("groups: [{name: broken, rules: [{record: x, expr: 'sum('}]}]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reload(); err == nil {
+		t.Error("expected reload of invalid rule file to fail")
+	}
+	if len(m.Groups()) != 1 {
+		t.Errorf("expected previous rule groups to be kept, got %+v", m.Groups())
+	}
+}
+
+func TestManagerChecksExpressions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rules.yml"), []byte(ruleFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	checkExpr := func(expr string) error {
+		if strings.Contains(expr, "> 100") {
+			return errors.New("not allowed")
+		}
+		return nil
+	}
+	_, err := NewManager(slog.New(slog.DiscardHandler), []string{filepath.Join(dir, "*.yml")}, checkExpr)
+	if err == nil || !strings.Contains(err.Error(), `rule "HighErrorRate": not allowed`) {
+		t.Errorf("expected rule check to fail, got %v", err)
+	}
+}
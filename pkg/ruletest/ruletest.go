@@ -0,0 +1,402 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ruletest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	promparser "github.com/prometheus/prometheus/promql/parser"
+	prom_httputil "github.com/prometheus/prometheus/util/httputil"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/prometheus/promlens/pkg/grafana"
+	"github.com/prometheus/promlens/pkg/parser"
+	"github.com/prometheus/promlens/pkg/promproxy"
+)
+
+const (
+	defaultInterval  = time.Minute
+	defaultMaxSeries = 5
+	maxMaxSeries     = 100
+	// maxSamples limits the number of samples per input series.
+	maxSamples     = 11000
+	prefillTimeout = 30 * time.Second
+)
+
+// testFile is the subset of the promtool unit test file format that we generate.
+type testFile struct {
+	EvaluationInterval model.Duration `yaml:"evaluation_interval"`
+	Tests              []testGroup    `yaml:"tests"`
+}
+
+type testGroup struct {
+	Interval        model.Duration `yaml:"interval"`
+	InputSeries     []series       `yaml:"input_series"`
+	PromQLExprTests []exprTest     `yaml:"promql_expr_test"`
+}
+
+type series struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+type exprTest struct {
+	Expr       string         `yaml:"expr"`
+	EvalTime   model.Duration `yaml:"eval_time"`
+	ExpSamples []sample       `yaml:"exp_samples"`
+}
+
+type sample struct {
+	Labels string  `yaml:"labels"`
+	Value  float64 `yaml:"value"`
+}
+
+// selector is a distinct selector of the expression along with the input
+// series labels that are derived from its equality matchers.
+type selector struct {
+	vs     *promparser.VectorSelector
+	series string
+	// inexact is set when the selector has matchers that can't be expressed
+	// as a single input series label set.
+	inexact bool
+}
+
+func selectors(expr promparser.Expr) []selector {
+	var (
+		sels []selector
+		seen = map[string]bool{}
+	)
+	promparser.Inspect(expr, func(node promparser.Node, _ []promparser.Node) error {
+		vs, ok := node.(*promparser.VectorSelector)
+		if !ok {
+			return nil
+		}
+
+		lbls := labels.NewBuilder(labels.EmptyLabels())
+		inexact := false
+		for _, m := range vs.LabelMatchers {
+			if m.Type == labels.MatchEqual {
+				lbls.Set(m.Name, m.Value)
+			} else {
+				inexact = true
+			}
+		}
+		s := seriesString(lbls.Labels())
+		if !seen[s] {
+			seen[s] = true
+			sels = append(sels, selector{vs: vs, series: s, inexact: inexact})
+		}
+		return nil
+	})
+	return sels
+}
+
+// seriesString formats a label set in the series notation used by promtool,
+// e.g. 'http_requests_total{job="api"}'.
+func seriesString(lbls labels.Labels) string {
+	name := lbls.Get(labels.MetricName)
+	rest := labels.NewBuilder(lbls).Del(labels.MetricName).Labels()
+	if rest.IsEmpty() {
+		return name
+	}
+	return name + rest.String()
+}
+
+// errLookbackOverflow is returned for expressions whose lookback doesn't fit
+// into a time.Duration.
+var errLookbackOverflow = errors.New("expression reads samples too far back in time")
+
+// addDurations returns the sum of the durations, or errLookbackOverflow if it
+// overflows.
+func addDurations(ds ...time.Duration) (time.Duration, error) {
+	var sum time.Duration
+	for _, d := range ds {
+		if (d > 0 && sum > math.MaxInt64-d) || (d < 0 && sum < math.MinInt64-d) {
+			return 0, errLookbackOverflow
+		}
+		sum += d
+	}
+	return sum, nil
+}
+
+// lookback returns how far back in time from the evaluation time an expression
+// reads samples.
+func lookback(node promparser.Node, interval time.Duration) (time.Duration, error) {
+	switch n := node.(type) {
+	case *promparser.MatrixSelector:
+		return addDurations(n.Range, n.VectorSelector.(*promparser.VectorSelector).OriginalOffset)
+	case *promparser.VectorSelector:
+		return addDurations(n.OriginalOffset, interval)
+	case *promparser.SubqueryExpr:
+		d, err := lookback(n.Expr, interval)
+		if err != nil {
+			return 0, err
+		}
+		return addDurations(n.Range, n.OriginalOffset, d)
+	}
+
+	var d time.Duration
+	for _, c := range promparser.Children(node) {
+		cd, err := lookback(c, interval)
+		if err != nil {
+			return 0, err
+		}
+		d = max(d, cd)
+	}
+	return d, nil
+}
+
+func stubValues(sel selector, steps int) string {
+	if strings.HasSuffix(sel.vs.Name, "_total") {
+		return fmt.Sprintf("0+1x%d", steps)
+	}
+	return fmt.Sprintf("1+0x%d", steps)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// prefill queries the series of a selector from Prometheus over the test
+// window and returns them as input series with the fetched sample values.
+func prefill(ctx context.Context, api v1.API, sel selector, end time.Time, evalTime, interval time.Duration, maxSeries int) ([]series, error) {
+	// Fetch the raw selector, without any range, offset, or @ modifier.
+	query := (&promparser.VectorSelector{Name: sel.vs.Name, LabelMatchers: sel.vs.LabelMatchers}).String()
+	start := end.Add(-evalTime)
+	val, _, err := api.QueryRange(ctx, query, v1.Range{Start: start, End: end, Step: interval})
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s: %w", query, err)
+	}
+	matrix, ok := val.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %q for %s", val.Type(), query)
+	}
+
+	steps := int(evalTime / interval)
+	out := make([]series, 0, min(len(matrix), maxSeries))
+	for _, ss := range matrix {
+		if len(out) == maxSeries {
+			break
+		}
+		vals := make([]string, steps+1)
+		for i := range vals {
+			vals[i] = "_"
+		}
+		for _, p := range ss.Values {
+			// Prometheus returns timestamps in milliseconds, so round to the
+			// nearest step instead of truncating a sub-millisecond offset.
+			i := int((p.Timestamp.Time().Sub(start) + interval/2) / interval)
+			if i >= 0 && i <= steps {
+				vals[i] = formatValue(float64(p.Value))
+			}
+		}
+		out = append(out, series{Series: ss.Metric.String(), Values: strings.Join(vals, " ")})
+	}
+	return out, nil
+}
+
+func parseIntParam(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// Handle generates a promtool unit test file skeleton for the expression in the
+// "expr" parameter. It contains stub input series for each selector in the
+// expression, an evaluation time that covers all samples the expression reads,
+// and a placeholder for the expected output.
+//
+// If the "prometheus_server" parameter names a configured Prometheus server, or
+// a Grafana backend is configured and the "datasource_id" parameter is set, the
+// input series are pre-filled with the series and sample values of each
+// selector, as fetched from that server or datasource over the test window
+// ending at the "time" parameter (or now).
+func Handle(features parser.Features, gb *grafana.Backend, pp *promproxy.Proxy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prom_httputil.SetCORS(w, parser.CORSOrigin, r)
+		input := r.FormValue("expr")
+		reqFeatures, err := parser.ParseFeatures(r.Form["enable_feature"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		interval := defaultInterval
+		if s := r.FormValue("interval"); s != "" {
+			d, err := model.ParseDuration(s)
+			if err != nil || d <= 0 {
+				http.Error(w, fmt.Sprintf("Invalid interval %q", s), http.StatusBadRequest)
+				return
+			}
+			interval = time.Duration(d)
+		}
+		maxSeries, err := parseIntParam(r.FormValue("max_series"), defaultMaxSeries)
+		if err != nil || maxSeries <= 0 || maxSeries > maxMaxSeries {
+			http.Error(w, fmt.Sprintf("The \"max_series\" parameter must be a number between 1 and %d", maxMaxSeries), http.StatusBadRequest)
+			return
+		}
+
+		expr, err := parser.ParseExpr(input, features.Merge(reqFeatures))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Expression incomplete or buggy: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Round the evaluation time up to a multiple of the interval, so that
+		// the last input sample is written exactly at the evaluation time.
+		evalTime, err := lookback(expr, interval)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		evalTime = max(evalTime, interval)
+		steps := int(evalTime / interval)
+		if evalTime%interval != 0 {
+			steps++
+		}
+		if steps+1 > maxSamples {
+			http.Error(w, fmt.Sprintf("Expression reads too many samples at an interval of %s, try a longer interval", model.Duration(interval)), http.StatusBadRequest)
+			return
+		}
+		if time.Duration(steps) > math.MaxInt64/interval {
+			http.Error(w, errLookbackOverflow.Error(), http.StatusBadRequest)
+			return
+		}
+		evalTime = time.Duration(steps) * interval
+
+		var api v1.API
+		end := time.Now()
+		server, dsID := r.FormValue("prometheus_server"), r.FormValue("datasource_id")
+		switch {
+		case server != "" && dsID != "":
+			http.Error(w, `The "prometheus_server" and "datasource_id" parameters are mutually exclusive`, http.StatusBadRequest)
+			return
+		case server != "":
+			if api, err = pp.PrometheusAPI(server); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, promproxy.ErrUnknownServer) {
+					status = http.StatusBadRequest
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+		case dsID != "":
+			if gb == nil {
+				http.Error(w, "No Grafana backend configured.", http.StatusBadRequest)
+				return
+			}
+			id, err := strconv.ParseInt(dsID, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid datasource ID %q", dsID), http.StatusBadRequest)
+				return
+			}
+			if api, err = gb.PrometheusAPI(r, id); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, grafana.ErrNoUserCredentials) {
+					status = http.StatusUnauthorized
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+		}
+		if t := r.FormValue("time"); t != "" && api != nil {
+			if end, err = parseTime(t); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), prefillTimeout)
+		defer cancel()
+
+		var (
+			comments []string
+			inputs   []series
+		)
+		for _, sel := range selectors(expr) {
+			if api != nil {
+				ss, err := prefill(ctx, api, sel, end, evalTime, interval, maxSeries)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("Error pre-filling input series: %v", err), http.StatusBadGateway)
+					return
+				}
+				if len(ss) > 0 {
+					inputs = append(inputs, ss...)
+					continue
+				}
+				comments = append(comments, fmt.Sprintf("Selector %s returned no series, using a stub input series.", sel.vs))
+			}
+			if sel.inexact {
+				comments = append(comments, fmt.Sprintf("Selector %s has non-equality matchers, adjust the labels of its input series.", sel.vs))
+			}
+			inputs = append(inputs, series{Series: sel.series, Values: stubValues(sel, steps)})
+		}
+
+		out, err := yaml.Marshal(testFile{
+			EvaluationInterval: model.Duration(interval),
+			Tests: []testGroup{{
+				Interval:    model.Duration(interval),
+				InputSeries: inputs,
+				PromQLExprTests: []exprTest{{
+					Expr:       expr.String(),
+					EvalTime:   model.Duration(evalTime),
+					ExpSamples: []sample{{Labels: "{}", Value: 0}},
+				}},
+			}},
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error marshaling test file: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/yaml")
+		fmt.Fprintln(w, "# Unit test skeleton for use with 'promtool test rules'.")
+		fmt.Fprintln(w, "# Replace the placeholder in exp_samples with the expected query result.")
+		for _, c := range comments {
+			fmt.Fprintf(w, "# %s\n", c)
+		}
+		w.Write(out)
+	}
+}
+
+// parseTime parses a time given as a (floating point) Unix timestamp or in RFC3339 format.
+func parseTime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		s, ns := math.Modf(t)
+		return time.Unix(int64(s), int64(ns*float64(time.Second))).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
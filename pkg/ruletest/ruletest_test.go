@@ -0,0 +1,259 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ruletest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/config"
+	promparser "github.com/prometheus/prometheus/promql/parser"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/prometheus/promlens/pkg/parser"
+	"github.com/prometheus/promlens/pkg/promproxy"
+)
+
+func TestLookback(t *testing.T) {
+	for _, tc := range []struct {
+		expr    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{expr: `x`, want: time.Minute},
+		{expr: `x offset 10m`, want: 11 * time.Minute},
+		{expr: `rate(x[5m])`, want: 5 * time.Minute},
+		{expr: `rate(x[5m] offset 1h)`, want: time.Hour + 5*time.Minute},
+		{expr: `x + rate(y[1h])`, want: time.Hour},
+		{expr: `max_over_time(rate(x[5m])[1h:1m])`, want: time.Hour + 5*time.Minute},
+		{expr: `max_over_time(x[1h:1m] offset 1d)`, want: 25*time.Hour + time.Minute},
+		{expr: `vector(1)`, want: 0},
+		{expr: `x offset -5m`, want: -4 * time.Minute},
+		{expr: `rate(x[200y] offset 200y)`, wantErr: true},
+		{expr: `max_over_time(rate(x[200y])[100y:1m])`, wantErr: true},
+		{expr: `max_over_time(x[10y:1m] offset 290y)`, wantErr: true},
+		{expr: `rate(x[150y] offset 150y)`, wantErr: true},
+	} {
+		expr, err := promparser.ParseExpr(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := lookback(expr, time.Minute)
+		if tc.wantErr {
+			if !errors.Is(err, errLookbackOverflow) {
+				t.Errorf("lookback(%s): expected overflow error, got %s, %v", tc.expr, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("lookback(%s) = %s, %v, want %s", tc.expr, got, err, tc.want)
+		}
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	expr, err := promparser.ParseExpr(`rate(http_requests_total{job="api"}[5m]) / rate(http_requests_total{job="api"}[1h]) + on() up{instance=~"a.*"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sels := selectors(expr)
+	if len(sels) != 2 {
+		t.Fatalf("expected 2 distinct selectors, got %d", len(sels))
+	}
+	if sels[0].series != `http_requests_total{job="api"}` || sels[0].inexact {
+		t.Errorf("unexpected first selector %+v", sels[0])
+	}
+	if sels[1].series != "up" || !sels[1].inexact {
+		t.Errorf("unexpected second selector %+v", sels[1])
+	}
+	if got := stubValues(sels[0], 5); got != "0+1x5" {
+		t.Errorf("unexpected counter stub values %q", got)
+	}
+	if got := stubValues(sels[1], 5); got != "1+0x5" {
+		t.Errorf("unexpected gauge stub values %q", got)
+	}
+}
+
+// newPrometheus returns a proxy with a server named "prom" (and one named
+// "direct" with direct access) that answers range queries with two series,
+// with a sample at every step except the first one.
+func newPrometheus(t *testing.T) *promproxy.Proxy {
+	t.Helper()
+	prom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			http.NotFound(w, r)
+			return
+		}
+		// Like Prometheus, align the samples to the start time in milliseconds.
+		start, _ := parseTime(r.FormValue("start"))
+		step, _ := time.ParseDuration(r.FormValue("step") + "s")
+		var values []string
+		for i := 1; i <= 2; i++ {
+			ts := start.Truncate(time.Millisecond).Add(time.Duration(i) * step)
+			values = append(values, fmt.Sprintf("[%.3f,\"%d\"]", float64(ts.UnixMilli())/1000, i))
+		}
+		var series []string
+		for _, inst := range []string{"a", "b"} {
+			series = append(series, fmt.Sprintf(`{"metric":{"__name__":"up","instance":%q},"values":[%s]}`, inst, strings.Join(values, ",")))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, strings.Join(series, ","))
+	}))
+	t.Cleanup(prom.Close)
+
+	u, err := url.Parse(prom.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp := promproxy.New(slog.New(slog.DiscardHandler))
+	var cfgs []promproxy.ServerConfig
+	for name, access := range map[string]string{"prom": promproxy.AccessProxy, "direct": promproxy.AccessDirect} {
+		cfgs = append(cfgs, promproxy.ServerConfig{
+			Name:             name,
+			URL:              &config.URL{URL: u},
+			Access:           access,
+			HTTPClientConfig: config.DefaultHTTPClientConfig,
+		})
+	}
+	servers, err := pp.NewServers(cfgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp.SetServers(servers)
+	return pp
+}
+
+func TestHandle(t *testing.T) {
+	pp := newPrometheus(t)
+
+	for _, tc := range []struct {
+		name       string
+		params     url.Values
+		status     int
+		wantInputs []series
+		wantEval   string
+		wantError  string
+	}{
+		{
+			name:       "stub",
+			params:     url.Values{"expr": {`rate(http_requests_total{job="api"}[5m])`}},
+			status:     http.StatusOK,
+			wantInputs: []series{{Series: `http_requests_total{job="api"}`, Values: "0+1x5"}},
+			wantEval:   "5m",
+		},
+		{
+			name:       "interval",
+			params:     url.Values{"expr": {`rate(x[5m])`}, "interval": {"2m"}},
+			status:     http.StatusOK,
+			wantInputs: []series{{Series: "x", Values: "1+0x3"}},
+			wantEval:   "6m",
+		},
+		{
+			name:   "prefill from Prometheus server",
+			params: url.Values{"expr": {`up`}, "prometheus_server": {"prom"}, "time": {"1700000000"}},
+			status: http.StatusOK,
+			wantInputs: []series{
+				{Series: `up{instance="a"}`, Values: "_ 1"},
+				{Series: `up{instance="b"}`, Values: "_ 1"},
+			},
+			wantEval: "1m",
+		},
+		{
+			name:       "prefill from direct server",
+			params:     url.Values{"expr": {`up`}, "prometheus_server": {"direct"}, "max_series": {"1"}},
+			status:     http.StatusOK,
+			wantInputs: []series{{Series: `up{instance="a"}`, Values: "_ 1"}},
+			wantEval:   "1m",
+		},
+		{
+			name:      "unknown server",
+			params:    url.Values{"expr": {`up`}, "prometheus_server": {"foo"}},
+			status:    http.StatusBadRequest,
+			wantError: `unknown Prometheus server "foo"`,
+		},
+		{
+			name:      "server and datasource",
+			params:    url.Values{"expr": {`up`}, "prometheus_server": {"prom"}, "datasource_id": {"1"}},
+			status:    http.StatusBadRequest,
+			wantError: "mutually exclusive",
+		},
+		{
+			name:      "datasource without Grafana",
+			params:    url.Values{"expr": {`up`}, "datasource_id": {"1"}},
+			status:    http.StatusBadRequest,
+			wantError: "No Grafana backend configured",
+		},
+		{
+			name:      "too many samples",
+			params:    url.Values{"expr": {`rate(x[30d])`}, "interval": {"1s"}},
+			status:    http.StatusBadRequest,
+			wantError: "too many samples",
+		},
+		{
+			name:      "lookback overflow",
+			params:    url.Values{"expr": {`rate(x[200y] offset 200y)`}},
+			status:    http.StatusBadRequest,
+			wantError: "too far back in time",
+		},
+		{
+			// A lookback of 250y rounded up to the 200y interval overflows.
+			name:      "evaluation time overflow",
+			params:    url.Values{"expr": {`rate(x[250y])`}, "interval": {"200y"}},
+			status:    http.StatusBadRequest,
+			wantError: "too far back in time",
+		},
+		{
+			name:      "invalid max_series",
+			params:    url.Values{"expr": {`x`}, "max_series": {"1000"}},
+			status:    http.StatusBadRequest,
+			wantError: "max_series",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handle(parser.Features{}, nil, pp)(rec, httptest.NewRequest(http.MethodGet, "/api/rule_test?"+tc.params.Encode(), nil))
+			if rec.Code != tc.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tc.status, rec.Body.String())
+			}
+			if tc.wantError != "" {
+				if !strings.Contains(rec.Body.String(), tc.wantError) {
+					t.Errorf("got error %q, want error containing %q", rec.Body.String(), tc.wantError)
+				}
+				return
+			}
+
+			var tf testFile
+			if err := yaml.Unmarshal(rec.Body.Bytes(), &tf); err != nil {
+				t.Fatal(err)
+			}
+			if len(tf.Tests) != 1 || len(tf.Tests[0].PromQLExprTests) != 1 {
+				t.Fatalf("unexpected test file:\n%s", rec.Body.String())
+			}
+			inputs := tf.Tests[0].InputSeries
+			if fmt.Sprint(inputs) != fmt.Sprint(tc.wantInputs) {
+				t.Errorf("got input series %v, want %v", inputs, tc.wantInputs)
+			}
+			if got := tf.Tests[0].PromQLExprTests[0].EvalTime.String(); got != tc.wantEval {
+				t.Errorf("got eval time %s, want %s", got, tc.wantEval)
+			}
+		})
+	}
+}
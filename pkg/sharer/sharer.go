@@ -29,6 +29,8 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/prometheus/promlens/pkg/audit"
+
 	// Load SQL drivers.
 	_ "github.com/glebarez/go-sqlite"
 	_ "github.com/go-sql-driver/mysql"
@@ -388,7 +390,7 @@ func shortName(pageState string) string {
 	return string(b)[:hashLen]
 }
 
-func Handle(logger *slog.Logger, s Sharer) http.HandlerFunc {
+func Handle(logger *slog.Logger, s Sharer, al *audit.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if s == nil {
 			http.Error(w, "No link sharing backend configured.", http.StatusServiceUnavailable)
@@ -427,6 +429,7 @@ func Handle(logger *slog.Logger, s Sharer) http.HandlerFunc {
 				http.Error(w, "Server Error", http.StatusInternalServerError)
 				return
 			}
+			al.Log(r, audit.Event{Action: audit.ActionCreateLink, Link: name})
 
 			fmt.Fprint(w, name)
 
@@ -14,6 +14,8 @@
 package web
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -23,25 +25,54 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	toolkitweb "github.com/prometheus/exporter-toolkit/web"
 
+	"github.com/prometheus/promlens/pkg/audit"
+	"github.com/prometheus/promlens/pkg/auth"
+	"github.com/prometheus/promlens/pkg/functiondocs"
 	"github.com/prometheus/promlens/pkg/grafana"
 	"github.com/prometheus/promlens/pkg/pageconfig"
 	"github.com/prometheus/promlens/pkg/parser"
+	"github.com/prometheus/promlens/pkg/promproxy"
 	"github.com/prometheus/promlens/pkg/react"
+	"github.com/prometheus/promlens/pkg/rules"
+	"github.com/prometheus/promlens/pkg/ruletest"
 	"github.com/prometheus/promlens/pkg/sharer"
 )
 
-// Config configures the PromLens web UI and API.
-type Config struct {
-	Logger                     *slog.Logger
-	ToolkitConfig              *toolkitweb.FlagConfig
-	RoutePrefix                string
-	ExternalURL                *url.URL
+// Components are the parts of PromLens that can be replaced at runtime when
+// the configuration is reloaded.
+type Components struct {
 	Sharer                     sharer.Sharer
 	GrafanaBackend             *grafana.Backend
 	DefaultPrometheusURL       string
 	DefaultGrafanaDatasourceID int64
+	PrometheusServers          []pageconfig.PrometheusServer
+	// Authenticator authenticates all requests, or is nil if authentication
+	// is disabled.
+	Authenticator *auth.Authenticator
 }
 
+// Config configures the PromLens web UI and API.
+type Config struct {
+	Logger          *slog.Logger
+	ToolkitConfig   *toolkitweb.FlagConfig
+	RoutePrefix     string
+	ExternalURL     *url.URL
+	PrometheusProxy *promproxy.Proxy
+	ParserFeatures  parser.Features
+	RuleManager     *rules.Manager
+	AuditLogger     *audit.Logger
+	// Components returns the currently active reloadable components, along
+	// with a function to call when the request that uses them is done, so
+	// that replaced components are only closed once they are no longer used.
+	Components func() (Components, func())
+	// Reload reloads the configuration, or is nil if reloading via HTTP is
+	// disabled.
+	Reload func() error
+}
+
+// componentsKey is the context key of the components that serve a request.
+type componentsKey struct{}
+
 // Serve serves the PromLens web UI and API.
 func Serve(cfg *Config) error {
 	prometheus.MustRegister(versioncollector.NewCollector("promlens"))
@@ -84,15 +115,82 @@ func Serve(cfg *Config) error {
 		cfg.RoutePrefix = ""
 	}
 
-	http.HandleFunc(cfg.RoutePrefix+"/api/page_config", instr("/api/page_config", pageconfig.Handle(cfg.Sharer, cfg.GrafanaBackend, cfg.DefaultPrometheusURL, cfg.DefaultGrafanaDatasourceID)))
-	http.HandleFunc(cfg.RoutePrefix+"/api/link", instr("/api/link", sharer.Handle(cfg.Logger, cfg.Sharer)))
-	http.HandleFunc(cfg.RoutePrefix+"/api/parse", instr("/api/parse", parser.Handle))
-	if cfg.GrafanaBackend != nil {
-		http.HandleFunc(cfg.RoutePrefix+"/api/grafana/", instr("/api/grafana", cfg.GrafanaBackend.Handle(cfg.RoutePrefix)))
+	// current wraps a handler that depends on the reloadable components, so
+	// that each request is served by the components that were active when it
+	// started.
+	current := func(handler func(c Components) http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			c, _ := r.Context().Value(componentsKey{}).(Components)
+			handler(c)(w, r)
+		}
+	}
+
+	http.HandleFunc(cfg.RoutePrefix+"/api/page_config", instr("/api/page_config", current(func(c Components) http.HandlerFunc {
+		return pageconfig.Handle(c.Sharer, c.GrafanaBackend, c.DefaultPrometheusURL, c.DefaultGrafanaDatasourceID, c.PrometheusServers, cfg.ParserFeatures.Names(), cfg.AuditLogger)
+	})))
+	http.HandleFunc(cfg.RoutePrefix+"/api/link", instr("/api/link", current(func(c Components) http.HandlerFunc {
+		return sharer.Handle(cfg.Logger, c.Sharer, cfg.AuditLogger)
+	})))
+	http.HandleFunc(cfg.RoutePrefix+"/api/parse", instr("/api/parse", parser.Handle(cfg.ParserFeatures, cfg.RuleManager)))
+	http.HandleFunc(cfg.RoutePrefix+"/api/parse_batch", instr("/api/parse_batch", parser.HandleBatch(cfg.ParserFeatures, cfg.RuleManager)))
+	http.HandleFunc(cfg.RoutePrefix+"/api/serialize", instr("/api/serialize", parser.HandleSerialize(cfg.ParserFeatures)))
+	http.HandleFunc(cfg.RoutePrefix+"/api/diff", instr("/api/diff", parser.HandleDiff(cfg.ParserFeatures)))
+	http.HandleFunc(cfg.RoutePrefix+"/api/analyze", instr("/api/analyze", parser.HandleAnalyze(cfg.ParserFeatures)))
+	http.HandleFunc(cfg.RoutePrefix+"/api/rule_export", instr("/api/rule_export", parser.HandleRuleExport(cfg.ParserFeatures)))
+	http.HandleFunc(cfg.RoutePrefix+"/api/rules", instr("/api/rules", rules.Handle(cfg.RuleManager)))
+	http.HandleFunc(cfg.RoutePrefix+"/api/rule_test", instr("/api/rule_test", current(func(c Components) http.HandlerFunc {
+		return ruletest.Handle(cfg.ParserFeatures, c.GrafanaBackend, cfg.PrometheusProxy)
+	})))
+	http.HandleFunc(cfg.RoutePrefix+"/api/functions", instr("/api/functions", parser.HandleFunctions))
+	http.HandleFunc(cfg.RoutePrefix+"/api/function_docs", instr("/api/function_docs", functiondocs.Handle))
+	http.HandleFunc(cfg.RoutePrefix+"/api/grafana/", instr("/api/grafana", current(func(c Components) http.HandlerFunc {
+		if c.GrafanaBackend == nil {
+			return http.NotFound
+		}
+		return c.GrafanaBackend.Handle(cfg.RoutePrefix, cfg.AuditLogger)
+	})))
+	http.HandleFunc(cfg.RoutePrefix+"/api/dashboard_import", instr("/api/dashboard_import", current(func(c Components) http.HandlerFunc {
+		if c.GrafanaBackend == nil {
+			return http.NotFound
+		}
+		return c.GrafanaBackend.HandleDashboardImport()
+	})))
+	http.HandleFunc(cfg.RoutePrefix+"/api/dashboard_export", instr("/api/dashboard_export", current(func(c Components) http.HandlerFunc {
+		if c.GrafanaBackend == nil {
+			return http.NotFound
+		}
+		return c.GrafanaBackend.HandleDashboardExport(cfg.AuditLogger)
+	})))
+	http.HandleFunc(cfg.RoutePrefix+"/api/prometheus/", instr("/api/prometheus", cfg.PrometheusProxy.Handle(cfg.RoutePrefix, cfg.AuditLogger)))
+	if cfg.Reload != nil {
+		http.HandleFunc(cfg.RoutePrefix+"/-/reload", instr("/-/reload", handleReload(cfg.Reload)))
 	}
 	http.HandleFunc(cfg.RoutePrefix+"/metrics", instr("/metrics", promhttp.Handler().ServeHTTP))
 	http.HandleFunc(cfg.RoutePrefix+"/", instr("static", react.Handle(cfg.RoutePrefix, cfg.ExternalURL)))
 
-	server := &http.Server{}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, done := cfg.Components()
+			defer done()
+			r = r.WithContext(context.WithValue(r.Context(), componentsKey{}, c))
+			if c.Authenticator == nil {
+				http.DefaultServeMux.ServeHTTP(w, r)
+				return
+			}
+			c.Authenticator.Handle(cfg.RoutePrefix, http.DefaultServeMux).ServeHTTP(w, r)
+		}),
+	}
 	return toolkitweb.ListenAndServe(server, cfg.ToolkitConfig, cfg.Logger)
 }
+
+func handleReload(reload func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			http.Error(w, "Invalid HTTP method, use POST or PUT", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reload(); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload config: %v", err), http.StatusInternalServerError)
+		}
+	}
+}